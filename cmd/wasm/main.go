@@ -0,0 +1,16 @@
+//go:build js && wasm
+
+// Command wasm runs the interpreter in the browser. Build with
+//
+//	GOOS=js GOARCH=wasm go build -o chip8.wasm ./cmd/wasm
+//
+// and load it next to Go's wasm_exec.js; it installs a global chip8 object
+// (see wasm.Register) and then waits to be called.
+package main
+
+import "github.com/l4rma/chip-8/wasm"
+
+func main() {
+	wasm.Register(wasm.NewSession())
+	select {}
+}
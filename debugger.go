@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/l4rma/chip-8/interpreter"
+)
+
+// runLimit caps how many instructions "run" executes without reaching a
+// breakpoint.
+const runLimit = 1 << 20
+
+const debugHelp = `step [n]          execute n instructions (1)
+run               execute until a breakpoint or an error
+break [addr]      set a breakpoint, or list them
+delete addr       remove a breakpoint
+regs              show registers, stack and timers
+mem addr [n]      hex dump n bytes (16)
+disasm addr [n]   disassemble n instructions (10)
+quit              leave the debugger
+`
+
+// debugTarget is what the debugger needs from the interpreter.
+type debugTarget interface {
+	StepInfo() (interpreter.StepResult, error)
+	DumpState() string
+	HexDump(addr uint16, n int) string
+	PeekMemory(addr uint16, n int) []byte
+}
+
+// debugger is the -debug REPL. Instructions run without frames, so the
+// display is not drawn and the timers do not count down.
+type debugger struct {
+	m      debugTarget
+	breaks map[uint16]bool
+}
+
+func newDebugger(m debugTarget) *debugger {
+	return &debugger{m: m, breaks: make(map[uint16]bool)}
+}
+
+// repl reads commands from r until quit or EOF, writing results to w.
+func (d *debugger) repl(r io.Reader, w io.Writer) error {
+	in := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "(chip8) ")
+		if !in.Scan() {
+			fmt.Fprintln(w)
+			return in.Err()
+		}
+		out, quit, err := d.exec(in.Text())
+		fmt.Fprint(w, out)
+		if err != nil {
+			fmt.Fprintf(w, "error: %s\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// exec runs one command line and returns its output and whether it was
+// quit. A failing instruction is reported as err after the output.
+func (d *debugger) exec(line string) (out string, quit bool, err error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false, nil
+	}
+	cmd, args := fields[0], fields[1:]
+	var b strings.Builder
+	switch cmd {
+	case "step", "s":
+		n, err := intArg(args, 0, 1)
+		if err != nil {
+			return "", false, err
+		}
+		for i := 0; i < n; i++ {
+			res, err := d.m.StepInfo()
+			fmt.Fprintf(&b, "%04X  %04X  %s\n", res.PCBefore, res.Opcode, interpreter.Disassemble(res.Opcode))
+			if err != nil {
+				return b.String(), false, err
+			}
+		}
+	case "run", "r":
+		for i := 0; i < runLimit; i++ {
+			res, err := d.m.StepInfo()
+			if err != nil {
+				return b.String(), false, err
+			}
+			if d.breaks[res.PCAfter] {
+				fmt.Fprintf(&b, "Breakpoint at %04X\n", res.PCAfter)
+				return b.String(), false, nil
+			}
+		}
+		return "", false, fmt.Errorf("no breakpoint reached in %d instructions", runLimit)
+	case "break", "b":
+		if len(args) == 0 {
+			var addrs []int
+			for addr := range d.breaks {
+				addrs = append(addrs, int(addr))
+			}
+			sort.Ints(addrs)
+			for _, addr := range addrs {
+				fmt.Fprintf(&b, "%04X\n", addr)
+			}
+			break
+		}
+		addr, err := addrArg(args, 0)
+		if err != nil {
+			return "", false, err
+		}
+		d.breaks[addr] = true
+	case "delete", "d":
+		addr, err := addrArg(args, 0)
+		if err != nil {
+			return "", false, err
+		}
+		delete(d.breaks, addr)
+	case "regs":
+		b.WriteString(d.m.DumpState())
+	case "mem", "m":
+		addr, err := addrArg(args, 0)
+		if err != nil {
+			return "", false, err
+		}
+		n, err := intArg(args, 1, 16)
+		if err != nil {
+			return "", false, err
+		}
+		b.WriteString(d.m.HexDump(addr, n))
+	case "disasm":
+		addr, err := addrArg(args, 0)
+		if err != nil {
+			return "", false, err
+		}
+		n, err := intArg(args, 1, 10)
+		if err != nil {
+			return "", false, err
+		}
+		mem := d.m.PeekMemory(addr, 2*n)
+		for i := 0; i+1 < len(mem); i += 2 {
+			op := uint16(mem[i])<<8 | uint16(mem[i+1])
+			fmt.Fprintf(&b, "%04X  %04X  %s\n", int(addr)+i, op, interpreter.Disassemble(op))
+		}
+	case "help", "h":
+		b.WriteString(debugHelp)
+	case "quit", "q":
+		return "", true, nil
+	default:
+		return "", false, fmt.Errorf("unknown command %q, try help", cmd)
+	}
+	return b.String(), false, nil
+}
+
+// addrArg parses args[i] as an address, decimal or 0x hex.
+func addrArg(args []string, i int) (uint16, error) {
+	if i >= len(args) {
+		return 0, fmt.Errorf("missing address")
+	}
+	v, err := strconv.ParseUint(args[i], 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", args[i])
+	}
+	return uint16(v), nil
+}
+
+// intArg parses the optional positive count args[i], def if absent.
+func intArg(args []string, i int, def int) (int, error) {
+	if i >= len(args) {
+		return def, nil
+	}
+	v, err := strconv.Atoi(args[i])
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid count %q", args[i])
+	}
+	return v, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/l4rma/chip-8/interpreter"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDebugger() *debugger {
+	chip8 := interpreter.NewChip8()
+	// LD V2, 0x69; ADD V2, 1; JP 0x202
+	chip8.LoadRomBytes([]byte{0x62, 0x69, 0x72, 0x01, 0x12, 0x02})
+	return newDebugger(chip8)
+}
+
+func TestDebuggerStep(t *testing.T) {
+	d := newTestDebugger()
+
+	out, quit, err := d.exec("step 2")
+
+	assert.NoError(t, err)
+	assert.False(t, quit)
+	assert.Equal(t, "0200  6269  LD V2, 0x69\n0202  7201  ADD V2, 0x01\n", out)
+
+	out, _, err = d.exec("regs")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "V2=6A")
+	assert.Contains(t, out, "PC=0204")
+}
+
+func TestDebuggerBreakAndRun(t *testing.T) {
+	d := newTestDebugger()
+
+	_, _, err := d.exec("break 0x204")
+	assert.NoError(t, err)
+	out, _, _ := d.exec("break")
+	assert.Equal(t, "0204\n", out)
+
+	out, _, err = d.exec("run")
+	assert.NoError(t, err)
+	assert.Equal(t, "Breakpoint at 0204\n", out)
+	out, _, err = d.exec("run")
+	assert.NoError(t, err)
+	assert.Equal(t, "Breakpoint at 0204\n", out, "around the loop once more")
+
+	_, _, err = d.exec("delete 0x204")
+	assert.NoError(t, err)
+	out, _, _ = d.exec("break")
+	assert.Empty(t, out)
+}
+
+func TestDebuggerMemAndDisasm(t *testing.T) {
+	d := newTestDebugger()
+
+	out, _, err := d.exec("mem 0x200 6")
+	assert.NoError(t, err)
+	assert.Equal(t, "0200: 62 69 72 01 12 02\n", out)
+
+	out, _, err = d.exec("disasm 0x200 3")
+	assert.NoError(t, err)
+	assert.Equal(t, "0200  6269  LD V2, 0x69\n0202  7201  ADD V2, 0x01\n0204  1202  JP 0x202\n", out)
+}
+
+func TestDebuggerErrors(t *testing.T) {
+	d := newTestDebugger()
+
+	for _, line := range []string{"bogus", "mem", "mem zz", "step 0", "break 0x10000"} {
+		_, quit, err := d.exec(line)
+		assert.Error(t, err, line)
+		assert.False(t, quit, line)
+	}
+
+	_, quit, err := d.exec("quit")
+	assert.NoError(t, err)
+	assert.True(t, quit)
+}
+
+func TestDebuggerREPL(t *testing.T) {
+	d := newTestDebugger()
+	var out strings.Builder
+
+	err := d.repl(strings.NewReader("step\n\nfoo\nq\nstep\n"), &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "(chip8) 0200  6269  LD V2, 0x69\n(chip8) (chip8) error: unknown command \"foo\", try help\n(chip8) ", out.String())
+}
@@ -0,0 +1,331 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Assemble translates CHIP-8 source in the mnemonic syntax Disassemble
+// produces into a ROM to be loaded at 0x200. Each line holds an optional
+// "label:", an optional instruction and an optional "; comment". Operands
+// are registers (V0-VF, I, DT, ST, K, F, HF, B, R, [I]), numbers (decimal or 0x
+// hex) or labels, e.g.
+//
+//	start:  LD V2, 0x69
+//	        DRW V0, V1, 5
+//	        JP start
+//
+// Besides the instructions, "DB b, ..." emits raw bytes, "DW w" a raw word
+// and "LD I, LONG addr" the XO-CHIP four byte F000 NNNN.
+func Assemble(src string) ([]byte, error) {
+	var lines []asmLine
+	labels := make(map[string]uint16)
+	addr := uint16(programStart)
+
+	// First pass: split lines and place labels
+	for i, text := range strings.Split(src, "\n") {
+		if j := strings.IndexByte(text, ';'); j >= 0 {
+			text = text[:j]
+		}
+		text = strings.TrimSpace(text)
+		if j := strings.IndexByte(text, ':'); j >= 0 {
+			label := strings.TrimSpace(text[:j])
+			if label == "" || strings.ContainsAny(label, " \t,") {
+				return nil, fmt.Errorf("line %d: invalid label %q", i+1, label)
+			}
+			if _, ok := labels[label]; ok {
+				return nil, fmt.Errorf("line %d: duplicate label %q", i+1, label)
+			}
+			labels[label] = addr
+			text = strings.TrimSpace(text[j+1:])
+		}
+		if text == "" {
+			continue
+		}
+		line := asmLine{num: i + 1}
+		mnemonic, operands := text, ""
+		if j := strings.IndexAny(text, " \t"); j >= 0 {
+			mnemonic, operands = text[:j], text[j+1:]
+		}
+		line.mnemonic = strings.ToUpper(mnemonic)
+		if operands = strings.TrimSpace(operands); operands != "" {
+			for _, arg := range strings.Split(operands, ",") {
+				line.args = append(line.args, strings.TrimSpace(arg))
+			}
+		}
+		lines = append(lines, line)
+		addr += line.size()
+	}
+
+	// Second pass: encode with every label known
+	var rom []byte
+	for _, line := range lines {
+		b, err := line.encode(labels)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line.num, err)
+		}
+		rom = append(rom, b...)
+	}
+	return rom, nil
+}
+
+type asmLine struct {
+	num      int
+	mnemonic string
+	args     []string
+}
+
+// size is how many bytes the line assembles to.
+func (l asmLine) size() uint16 {
+	switch {
+	case l.mnemonic == "DB":
+		return uint16(len(l.args))
+	case l.mnemonic == "LD" && len(l.args) == 2 && strings.HasPrefix(strings.ToUpper(l.args[1]), "LONG"):
+		return 4
+	}
+	return 2
+}
+
+func (l asmLine) encode(labels map[string]uint16) ([]byte, error) {
+	a := l.args
+	op, err := l.opcode(labels)
+	if err != nil {
+		return nil, err
+	}
+	if l.mnemonic == "DB" {
+		var b []byte
+		for _, arg := range a {
+			v, err := asmValue(arg, 0xFF, labels)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, byte(v))
+		}
+		return b, nil
+	}
+	b := []byte{byte(op >> 8), byte(op)}
+	if l.size() == 4 {
+		long := strings.TrimSpace(a[1][len("LONG"):])
+		v, err := asmValue(long, 0xFFFF, labels)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, byte(v>>8), byte(v))
+	}
+	return b, nil
+}
+
+// opcode encodes the first word of the line.
+func (l asmLine) opcode(labels map[string]uint16) (uint16, error) {
+	a := l.args
+	args := func(n int) error {
+		if len(a) != n {
+			return fmt.Errorf("%s takes %d operands, got %d", l.mnemonic, n, len(a))
+		}
+		return nil
+	}
+	value := func(i int, max uint16) (uint16, error) {
+		return asmValue(a[i], max, labels)
+	}
+	// regOp encodes op | x<<8 | y<<4 for two register operands
+	regOp := func(op uint16) (uint16, error) {
+		if err := args(2); err != nil {
+			return 0, err
+		}
+		x, okX := asmRegister(a[0])
+		y, okY := asmRegister(a[1])
+		if !okX || !okY {
+			return 0, fmt.Errorf("%s needs registers Vx, Vy", l.mnemonic)
+		}
+		return op | x<<8 | y<<4, nil
+	}
+	// regByteOrReg encodes "Vx, byte" as byteOp and "Vx, Vy" as regOpcode
+	regByteOrReg := func(byteOp, regOpcode uint16) (uint16, error) {
+		if err := args(2); err != nil {
+			return 0, err
+		}
+		x, ok := asmRegister(a[0])
+		if !ok {
+			return 0, fmt.Errorf("%s needs register Vx, got %q", l.mnemonic, a[0])
+		}
+		if _, ok := asmRegister(a[1]); ok {
+			return regOp(regOpcode)
+		}
+		kk, err := value(1, 0xFF)
+		return byteOp | x<<8 | kk, err
+	}
+	// fx encodes Fx?? / Ex?? style single register instructions
+	fx := func(op uint16, i int) (uint16, error) {
+		x, ok := asmRegister(a[i])
+		if !ok {
+			return 0, fmt.Errorf("%s needs register Vx, got %q", l.mnemonic, a[i])
+		}
+		return op | x<<8, nil
+	}
+
+	switch l.mnemonic {
+	case "CLS", "RET", "EXIT", "LOW", "HIGH", "AUDIO":
+		if err := args(0); err != nil {
+			return 0, err
+		}
+		return map[string]uint16{
+			"CLS": 0x00E0, "RET": 0x00EE, "EXIT": 0x00FD, "LOW": 0x00FE, "HIGH": 0x00FF, "AUDIO": 0xF002,
+		}[l.mnemonic], nil
+	case "SYS", "CALL":
+		if err := args(1); err != nil {
+			return 0, err
+		}
+		nnn, err := value(0, 0xFFF)
+		if l.mnemonic == "CALL" {
+			nnn |= 0x2000
+		}
+		return nnn, err
+	case "JP":
+		if len(a) == 2 && strings.ToUpper(a[0]) == "V0" {
+			nnn, err := value(1, 0xFFF)
+			return 0xB000 | nnn, err
+		}
+		if err := args(1); err != nil {
+			return 0, err
+		}
+		nnn, err := value(0, 0xFFF)
+		return 0x1000 | nnn, err
+	case "SE":
+		return regByteOrReg(0x3000, 0x5000)
+	case "SNE":
+		return regByteOrReg(0x4000, 0x9000)
+	case "OR":
+		return regOp(0x8001)
+	case "AND":
+		return regOp(0x8002)
+	case "XOR":
+		return regOp(0x8003)
+	case "SUB":
+		return regOp(0x8005)
+	case "SUBN":
+		return regOp(0x8007)
+	case "SHR", "SHL":
+		op := uint16(0x8006)
+		if l.mnemonic == "SHL" {
+			op = 0x800E
+		}
+		if len(a) == 1 {
+			a = append(a, a[0])
+		}
+		return regOp(op)
+	case "RND":
+		if err := args(2); err != nil {
+			return 0, err
+		}
+		op, err := fx(0xC000, 0)
+		if err != nil {
+			return 0, err
+		}
+		kk, err := value(1, 0xFF)
+		return op | kk, err
+	case "DRW":
+		if err := args(3); err != nil {
+			return 0, err
+		}
+		x, okX := asmRegister(a[0])
+		y, okY := asmRegister(a[1])
+		if !okX || !okY {
+			return 0, fmt.Errorf("DRW needs registers Vx, Vy")
+		}
+		n, err := value(2, 0xF)
+		return 0xD000 | x<<8 | y<<4 | n, err
+	case "SKP", "SKNP":
+		if err := args(1); err != nil {
+			return 0, err
+		}
+		if l.mnemonic == "SKP" {
+			return fx(0xE09E, 0)
+		}
+		return fx(0xE0A1, 0)
+	case "PLANE":
+		if err := args(1); err != nil {
+			return 0, err
+		}
+		n, err := value(0, 0x3)
+		return 0xF001 | n<<8, err
+	case "ADD":
+		if len(a) == 2 && strings.ToUpper(a[0]) == "I" {
+			return fx(0xF01E, 1)
+		}
+		return regByteOrReg(0x7000, 0x8004)
+	case "LD":
+		if err := args(2); err != nil {
+			return 0, err
+		}
+		dst, src := strings.ToUpper(a[0]), strings.ToUpper(a[1])
+		switch {
+		case dst == "I" && strings.HasPrefix(src, "LONG"):
+			return 0xF000, nil
+		case dst == "I":
+			nnn, err := value(1, 0xFFF)
+			return 0xA000 | nnn, err
+		case dst == "DT":
+			return fx(0xF015, 1)
+		case dst == "ST":
+			return fx(0xF018, 1)
+		case dst == "F":
+			return fx(0xF029, 1)
+		case dst == "HF":
+			return fx(0xF030, 1)
+		case dst == "B":
+			return fx(0xF033, 1)
+		case dst == "[I]":
+			return fx(0xF055, 1)
+		case src == "DT":
+			return fx(0xF007, 0)
+		case src == "K":
+			return fx(0xF00A, 0)
+		case src == "[I]":
+			return fx(0xF065, 0)
+		case dst == "R":
+			return fx(0xF075, 1)
+		case src == "R":
+			return fx(0xF085, 0)
+		}
+		return regByteOrReg(0x6000, 0x8000)
+	case "DW":
+		if err := args(1); err != nil {
+			return 0, err
+		}
+		return value(0, 0xFFFF)
+	case "DB":
+		if len(a) == 0 {
+			return 0, fmt.Errorf("DB needs at least one byte")
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("unknown mnemonic %q", l.mnemonic)
+}
+
+// asmRegister parses V0-VF.
+func asmRegister(s string) (uint16, bool) {
+	if len(s) != 2 || (s[0] != 'V' && s[0] != 'v') {
+		return 0, false
+	}
+	x, err := strconv.ParseUint(s[1:], 16, 4)
+	return uint16(x), err == nil
+}
+
+// asmValue parses a number or label no larger than max.
+func asmValue(s string, max uint16, labels map[string]uint16) (uint16, error) {
+	if v, ok := labels[s]; ok {
+		if v > max {
+			return 0, fmt.Errorf("label %q (0x%X) out of range", s, v)
+		}
+		return v, nil
+	}
+	v, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number or unknown label %q", s)
+	}
+	if uint16(v) > max {
+		return 0, fmt.Errorf("%s out of range (max 0x%X)", s, max)
+	}
+	return uint16(v), nil
+}
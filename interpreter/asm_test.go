@@ -0,0 +1,76 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssemble(t *testing.T) {
+	src := `
+; draw a digit until a key is pressed
+start:  LD V2, 0x69
+        LD I, sprite
+loop:   DRW V0, V1, 5
+        SKP V2
+        JP loop
+        CALL sub
+        JP start
+sub:    ADD V2, 1       ; bump the counter
+        RET
+sprite: DB 0xF0, 0x90, 0x90, 0x90, 0xF0
+`
+	rom, err := Assemble(src)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x62, 0x69, // 0x200 LD V2, 0x69
+		0xA2, 0x12, // 0x202 LD I, sprite
+		0xD0, 0x15, // 0x204 DRW V0, V1, 5
+		0xE2, 0x9E, // 0x206 SKP V2
+		0x12, 0x04, // 0x208 JP loop
+		0x22, 0x0E, // 0x20A CALL sub
+		0x12, 0x00, // 0x20C JP start
+		0x72, 0x01, // 0x20E ADD V2, 1
+		0x00, 0xEE, // 0x210 RET
+		0xF0, 0x90, 0x90, 0x90, 0xF0, // 0x212 sprite
+	}, rom)
+}
+
+func TestAssembleLongLoad(t *testing.T) {
+	rom, err := Assemble("LD I, LONG data\nJP 0x200\ndata: DW 0x1234")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xF0, 0x00, 0x02, 0x06, 0x12, 0x00, 0x12, 0x34}, rom)
+}
+
+func TestAssembleRoundTrip(t *testing.T) {
+	ops := []uint16{
+		0x00E0, 0x00EE, 0x00FD, 0x00FE, 0x00FF, 0x0123, 0x1204, 0x2300, 0x3269, 0x4269,
+		0x5240, 0x6269, 0x7269, 0x8230, 0x8231, 0x8232, 0x8233, 0x8234, 0x8235,
+		0x8236, 0x8237, 0x823E, 0x9240, 0xA666, 0xB600, 0xC2FF, 0xD015, 0xE59E,
+		0xE5A1, 0xF201, 0xF002, 0xF507, 0xF50A, 0xF515, 0xF518, 0xF51E, 0xF529, 0xF530,
+		0xF533, 0xF555, 0xF565, 0xF575, 0xF585,
+	}
+	for _, op := range ops {
+		rom, err := Assemble(Disassemble(op))
+		if assert.NoError(t, err, Disassemble(op)) {
+			assert.Equal(t, []byte{byte(op >> 8), byte(op)}, rom, Disassemble(op))
+		}
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []string{
+		"FOO V1",
+		"LD V2, 0x100",
+		"JP nowhere",
+		"DRW V0, V1",
+		"SE VG, 1",
+		"a: CLS\na: CLS",
+	}
+	for _, src := range tests {
+		_, err := Assemble(src)
+		assert.Error(t, err, src)
+	}
+}
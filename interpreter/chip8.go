@@ -2,72 +2,319 @@ package interpreter
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
+	"math"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var (
-	GraphicsWidth  uint16 = 0
-	GraphicsHeight uint16 = 0
-	ClockSpeed            = time.Duration(60) // 60Hz
+// Programs are loaded and start executing here; everything below is
+// reserved for the interpreter and font.
+const programStart = 0x200
+
+// Memory sizes accepted by NewChip8WithMemory.
+const (
+	DefaultMemorySize = 0x1000
+	MaxMemorySize     = 0x10000
+)
+
+// DefaultClockSpeed is the instructions per second NewChip8 starts at.
+const DefaultClockSpeed = 60
+
+// Range SetSpeedMultiplier clamps to.
+const (
+	MinSpeedMultiplier = 0.1
+	MaxSpeedMultiplier = 16
 )
 
 type chip8 struct {
-	memory     [0x1000]byte         // 4096 bytes internal memory
-	V          [0x10]byte           // 16 8-bit virtual registers (V0-VF)
-	I          uint16               // Address register
-	PC         uint16               // Program Counter (starts at 0x200)
-	SP         byte                 // Stack Pointer
-	stack      [0x10]uint16         // 16 cells of reserved memory
-	display    [64 * 2][32 * 2]byte // 64x32 pixel display
-	keypad     [16]byte             // Keypad with 16 keys
-	delayTimer byte
-	soundTimer byte
+	memory        []byte                                // 4096 bytes internal memory by default
+	V             [0x10]byte                            // 16 8-bit virtual registers (V0-VF)
+	I             uint16                                // Address register
+	PC            uint16                                // Program Counter (starts at the entry point)
+	SP            byte                                  // Stack Pointer (next free stack cell)
+	stack         [0x10]uint16                          // 16 cells of reserved memory
+	display       framebuffer                           // Both planes at high resolution
+	hires         bool                                  // SUPER-CHIP 128x64 mode
+	planes        byte                                  // XO-CHIP planes selected for drawing
+	dirty         bool                                  // Display changed since last presented
+	collision     bool                                  // Last DRW erased a lit pixel
+	lastDraw      drawRegion                            // Pixels the last DRW flipped
+	persistence   int                                   // Frames an unlit pixel takes to fade
+	fade          *[HighResHeight][HighResWidth]float32 // Pixel intensities with persistence
+	vblank        bool                                  // Waiting for the next frame to draw again
+	skipped       bool                                  // Last instruction skipped the next one
+	screen        Display                               // Frontend presenting the framebuffer
+	scale         int                                   // SetScale size, 0 for the defaults
+	onFrame       func(frame [][]bool)                  // Called after each frame that drew
+	keypad        [16]byte                              // Keypad with 16 keys
+	latched       uint16                                // Keys pressed since the last frame, one bit each
+	keyMap        map[rune]uint8                        // Physical key to hex key
+	delayTimer    byte
+	soundTimer    byte
+	sound         Sound
+	soundState    func(playing bool) // SetSoundStateCallback
+	soundChanges  []bool             // Beep starts and stops notifySound has yet to pass on
+	audioPattern  [16]byte           // XO-CHIP audio pattern buffer
+	hasPattern    bool               // F002 has loaded audioPattern
+	rplFlags      [8]byte            // SUPER-CHIP HP-48 RPL user flags
+	font          []byte             // Fx29 font, reloaded by Reset
+	fontBase      uint16             // Address of the Fx29 font
+	largeFont     []byte             // Fx30 font, reloaded by Reset
+	largeFontBase uint16             // Address of the Fx30 font
+	trace         TraceFunc
+	logger        Logger
+	watches       map[uint16][]*watchpoint
+	onCodeWrite   CodeWriteFunc
+	clockSpeed    int        // Instructions per second
+	speed         float64    // SetSpeedMultiplier factor on clockSpeed
+	cycleCarry    float64    // Fraction of an instruction owed to the next frame
+	paused        int32      // Set by Pause, accessed atomically
+	mu            sync.Mutex // Held while instructions execute, for Snapshot
+	rng           *rand.Rand
+	seed          int64        // What Seed seeded rng with
+	rngDraws      uint64       // Bytes drawn from rng since Seed
+	clock         Clock        // Paces Run, the wall clock by default
+	lastTick      time.Time    // When updateTimers last ran
+	cycles        uint64       // Instructions executed
+	recording     bool         // KeyDown and KeyUp append to events
+	events        []InputEvent // Recorded input
+	replay        []InputEvent // Input still to be replayed
+	keyEvents     chan KeyEvent
+	audio         AudioFunc
+	sampleRate    int
+	audioPhase    float64 // Position in the waveform, in periods
+	audioBuf      []float32
+	stats         map[uint16]uint64 // Executions per opcode with EnableStats
+	history       history           // Snapshots for StepBack
+	entry         int               // Where ROMs load and PC starts, 0x200 by default
+	displayMap    int               // Address of the MapDisplay mirror, 0 when off
+	romEnd        int               // Address past the last ROM byte loaded, 0 for none
+	segments      []segment         // Memory filled by LoadRomBytes and LoadAt
+
+	Options
+}
+
+// Options are the interpreter's quirks and behaviour switches, all off by
+// default. Config returns them for changing, before running a ROM.
+type Options struct {
+	Quirks                Quirks
+	HaltOnInfiniteLoop    bool // Return ErrHalt when 1nnn jumps to itself
+	ProtectReservedMemory bool // Fail stores below 0x200 with ProtectedWriteError
+	IgnoreSysCalls        bool // Treat 0nnn SYS as a no-op instead of an unknown opcode
+	BufferKeyPresses      bool // Keys tapped between frames stay down until the frame ends
+	StrictDecode          bool // Reject 5xyn, 8xyn and 9xyn with undefined low nibbles
+	SkipUnknownOpcodes    bool // Log unknown opcodes and step past them instead of failing
+	EnableStats           bool // Count executed opcodes for OpcodeStats
+	BCDWidth              int  // Digits Fx33 stores, at least and by default 3; more add leading zeros
+}
+
+// Config returns the machine's options, for setting Quirks and the other
+// switches through a Machine.
+func (c *chip8) Config() *Options {
+	return &c.Options
 }
 
-func NewChip8() chip8 {
-	return chip8{
-		PC: 0x200,
-		SP: 0,
+// NewChip8 returns an interpreter with the default 4KB of memory. Passing
+// the Machine around never copies its memory and display.
+func NewChip8() Machine {
+	return newChip8(DefaultMemorySize)
+}
+
+// NewChip8WithMemory returns an interpreter with size bytes of memory, up to
+// the 64KB that XO-CHIP's 16-bit addresses can reach. With less than 4KB,
+// jumps and calls past the end of memory fail with an *AddressError.
+func NewChip8WithMemory(size int) (Machine, error) {
+	if size <= programStart || size > MaxMemorySize {
+		return nil, fmt.Errorf("memory size %d outside 0x%X-0x%X", size, programStart+1, MaxMemorySize)
+	}
+	return newChip8(size), nil
+}
+
+func newChip8(memorySize int) *chip8 {
+	c := &chip8{
+		memory:     make([]byte, memorySize),
+		PC:         programStart,
+		SP:         0,
+		planes:     0x1,
+		sound:      nopSound{},
+		screen:     nopDisplay{},
+		logger:     nopLogger{},
+		clock:      realClock{},
+		keyMap:     DefaultKeyMap(),
+		clockSpeed: DefaultClockSpeed,
+		speed:      1,
+		entry:      programStart,
+		sampleRate: DefaultSampleRate,
+		keyEvents:  make(chan KeyEvent, keyEventBuffer),
 	}
+	c.SetFont(FontSet, FontBase)
+	c.SetLargeFont(LargeFontSet, LargeFontBase)
+	return c
+}
+
+// Reset puts the machine back in its power-on state: memory, registers,
+// stack, display, timers, keys and step history are cleared, both fonts
+// are reloaded at their bases, including any set with SetFont and
+// SetLargeFont, and PC returns to the entry point, so the ROM has to be
+// loaded again.
+// Configuration such as Quirks, the options, the clock speed, the key map
+// and the installed frontends is kept.
+func (c *chip8) Reset() {
+	for i := range c.memory {
+		c.memory[i] = 0
+	}
+	c.V = [0x10]byte{}
+	c.I = 0
+	c.PC = uint16(c.entry)
+	c.SP = 0
+	c.stack = [0x10]uint16{}
+	c.display = framebuffer{}
+	if c.fade != nil {
+		c.fade = new([HighResHeight][HighResWidth]float32)
+	}
+	c.hires = false
+	c.planes = 0x1
+	c.dirty = true
+	c.collision = false
+	c.lastDraw = drawRegion{}
+	c.vblank = false
+	c.skipped = false
+	for k, v := range c.keypad {
+		if v == 1 {
+			c.emitKey(KeyEvent{Key: uint8(k)})
+		}
+	}
+	c.keypad = [16]byte{}
+	c.latched = 0
+	c.delayTimer = 0
+	c.SetSoundTimer(0)
+	c.lastTick = time.Time{}
+	c.audioPattern = [16]byte{}
+	c.hasPattern = false
+	c.audioPhase = 0
+	c.rplFlags = [8]byte{}
+	c.cycles = 0
+	c.cycleCarry = 0
+	c.replay = nil
+	c.stats = nil
+	c.history.start, c.history.n = 0, 0
+	c.romEnd = 0
+	c.segments = nil
+	c.loadFont(c.font, c.fontBase, FontHeight)
+	c.loadFont(c.largeFont, c.largeFontBase, LargeFontHeight)
 }
 
 func (c *chip8) LoadRom(data io.Reader) (int, error) {
-	offset := 0x200
-	return c.load(offset, data)
+	info, err := c.LoadRomInfo(data)
+	return info.Loaded, err
+}
+
+// LoadRomBytes copies rom into memory at the entry point, 0x200 unless
+// SetEntryPoint moved it. Instructions are two bytes, so an odd-length ROM
+// is logged as a warning and padded with a zero byte, making the fetch of
+// its last byte see xx00 rather than whatever an earlier ROM left behind.
+func (c *chip8) LoadRomBytes(rom []byte) (int, error) {
+	if len(rom) > len(c.memory)-c.entry {
+		return 0, ErrRomTooLarge
+	}
+	n := copy(c.memory[c.entry:], rom)
+	if n%2 != 0 {
+		c.logger.Printf("Warning: ROM has an odd length of %d bytes, padding with 0x00", n)
+		if end := c.entry + n; end < len(c.memory) {
+			c.memory[end] = 0
+		}
+	}
+	c.romEnd = c.entry + n
+	c.segments = []segment{{c.entry, c.romEnd}}
+	return n, nil
+}
+
+// EntryPoint returns the address ROMs are loaded at and PC starts from.
+func (c *chip8) EntryPoint() uint16 {
+	return uint16(c.entry)
+}
+
+// SetEntryPoint moves where LoadRom and LoadRomBytes put the ROM and where
+// PC starts, including after Reset, for variants such as the ETI-660 that
+// run programs from 0x600. It also sets PC, so it belongs before loading
+// the ROM. Stores below 0x200 are still what ProtectReservedMemory guards.
+// An addr below 0x200 or without room for an instruction is an error and
+// leaves the entry point unchanged.
+func (c *chip8) SetEntryPoint(addr uint16) error {
+	if addr < programStart || int(addr) >= len(c.memory)-1 {
+		return fmt.Errorf("entry point 0x%04X outside 0x%X-0x%X", addr, programStart, len(c.memory)-2)
+	}
+	c.entry = int(addr)
+	c.PC = addr
+	return nil
+}
+
+// LoadRomFS loads the named ROM from fsys, such as an embed.FS bundled into
+// the binary.
+func (c *chip8) LoadRomFS(fsys fs.FS, name string) (int, error) {
+	rom, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return 0, err
+	}
+	return c.LoadRomBytes(rom)
 }
 
+// load reads r into memory at offset until EOF or the end of memory and
+// returns the number of bytes read.
 func (c *chip8) load(offset int, r io.Reader) (int, error) {
-	return r.Read(c.memory[offset:])
+	n, err := io.ReadFull(r, c.memory[offset:])
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return n, err
 }
 
+// LoadBytes copies b into memory at offset o, truncating it at the end of
+// memory. It does no other checks; LoadAt is the checked version.
 func (c *chip8) LoadBytes(o int, b []byte) (int, error) {
 	return c.load(o, bytes.NewReader(b))
 }
 
+// writeMemory is the path every instruction that stores to memory goes
+// through, so watchpoints see the write and ProtectReservedMemory can
+// refuse it. Loading with LoadBytes does not come through here.
+func (c *chip8) writeMemory(addr uint16, v byte) error {
+	if int(addr) >= len(c.memory) {
+		return &AddressError{Addr: int(addr), PC: c.PC - 2}
+	}
+	if c.ProtectReservedMemory && addr < programStart {
+		return &ProtectedWriteError{Addr: addr, PC: c.PC - 2}
+	}
+	old := c.memory[addr]
+	c.memory[addr] = v
+	if old != v && len(c.watches) > 0 {
+		c.notifyWatches(addr, old, v)
+	}
+	if c.onCodeWrite != nil && int(addr) >= c.entry && int(addr) < c.romEnd {
+		c.onCodeWrite(addr, c.PC-2)
+	}
+	return nil
+}
+
 func (c *chip8) PrintMemory(index int) {
 	fmt.Printf("CHIP-8 Memory[%d]: 0x%02X\n", index, c.memory[index])
 }
 
+// MemoryDump logs DumpState along with the current opcode.
 func (c *chip8) MemoryDump(opcode uint16) {
-	log.Printf("=== MEMORY DUMP ===")
-	var i int16
-	for i = 0; i < 16; i++ {
-		log.Printf("Register V[%d]: %02X", i, c.V[i])
-	}
-	log.Printf("Register I: %04X", c.I)
-	log.Printf("Current opcode: %04X", opcode)
+	c.logger.Printf("=== MEMORY DUMP ===\n%sCurrent opcode: %04X", c.DumpState(), opcode)
 }
 
+// clearDisplay clears the selected planes only.
 func (c *chip8) clearDisplay() {
-	for i := range c.display {
-		for j := range c.display[i] {
-			c.display[i][j] = 0
-		}
-	}
+	c.display.clear(c.planes)
+	c.dirty = true
 }
 
 func (c *chip8) loadKeys() {
@@ -75,6 +322,7 @@ func (c *chip8) loadKeys() {
 	for i = 0; i < 16; i++ {
 		c.keypad[i] = 0x00
 	}
+	c.latched = 0
 }
 
 func (c *chip8) Init() error {
@@ -83,147 +331,575 @@ func (c *chip8) Init() error {
 }
 
 func (c *chip8) Run() error {
+	return c.RunContext(context.Background())
+}
+
+// RunContext runs frames at 60Hz by the Clock, executing ClockSpeed()
+// instructions per second, until an instruction fails or ctx is cancelled,
+// in which case ctx.Err() is returned after the current frame. Frames the
+// host was too slow for are caught up on, see waitFrame. Pause holds it on
+// the current instruction with the display still presented every frame.
+func (c *chip8) RunContext(ctx context.Context) error {
 	err := c.Init()
 	if err != nil {
 		return err
 	}
 
+	next := c.clock.Now().Add(TimerPeriod)
 	for {
-		err := c.Step()
+		err := c.RunFrame()
 		if err != nil {
 			return err
 		}
-		time.Sleep(time.Second / ClockSpeed)
+		next = c.waitFrame(next)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 	}
 }
 
-func (c *chip8) Step() error {
-	opcode := c.FetchInstruction()
-	_, err := c.ExecuteOpcode(opcode)
+// RunFrame executes one 60Hz frame: the frame's share of ClockSpeed()
+// instructions, then a single timer tick and presenting the display. With
+// the DisplayWait quirk a DRW ends the frame early, as it waits for vblank
+// on the COSMAC VIP.
+//
+// While paused a frame only presents the display: no instructions run and
+// the timers hold their values.
+func (c *chip8) RunFrame() error {
+	return c.runFrame(nil)
+}
+
+// runFrame is RunFrame, asking brk before every instruction whether to
+// break there instead. A break pauses the machine and ends the frame early.
+func (c *chip8) runFrame(brk func() bool) error {
+	if c.Paused() {
+		c.present()
+		return nil
+	}
+	c.mu.Lock()
+	err := c.runSteps(brk)
+	var samples []float32
+	if err == nil {
+		if c.audio != nil {
+			samples = c.fillAudio()
+		}
+		c.updateTimers()
+		if c.fade != nil {
+			c.updateFade()
+		}
+	}
+	c.mu.Unlock()
+	// The Sound, the callbacks and the display may call Snapshot, so they
+	// are all run unlocked
+	c.notifySound()
 	if err != nil {
-		log.Printf("Exec opcode error: %s", err)
 		return err
 	}
+	if samples != nil {
+		c.audio(samples, c.sampleRate)
+	}
+	c.present()
+	c.latched = 0
+	return nil
+}
+
+// runSteps executes the frame's instructions with mu held.
+func (c *chip8) runSteps(brk func() bool) error {
+	c.vblank = false
+	n := c.cyclesPerFrame()
+	for i := 0; i < n && !c.vblank; i++ {
+		if brk != nil && brk() {
+			c.Pause()
+			break
+		}
+		if _, err := c.stepInfo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Seed makes Cxkk RND draw from its own generator seeded with seed, so runs
+// are repeatable. Without it RND uses the math/rand package generator.
+func (c *chip8) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+	c.seed, c.rngDraws = seed, 0
+}
+
+// random returns the next byte for Cxkk.
+func (c *chip8) random() byte {
+	if c.rng != nil {
+		c.rngDraws++
+		return byte(c.rng.Intn(256))
+	}
+	return byte(rand.Intn(256))
+}
+
+// Cycles returns how many instructions have executed successfully.
+func (c *chip8) Cycles() uint64 {
+	return c.cycles
+}
+
+// Registers returns a copy of V0-VF.
+func (c *chip8) Registers() [16]byte {
+	return c.V
+}
+
+// SetRegisters replaces V0-VF at once, for test setup and debuggers.
+func (c *chip8) SetRegisters(v [16]byte) {
+	c.V = v
+}
+
+// IndexRegister returns I.
+func (c *chip8) IndexRegister() uint16 {
+	return c.I
+}
+
+// SetIndexRegister sets I, e.g. to point a DRW at sprite data placed with
+// LoadAt.
+func (c *chip8) SetIndexRegister(addr uint16) {
+	c.I = addr
+}
+
+// State is a copy of the machine taken by Snapshot.
+type State struct {
+	Frame      [][]byte // As Frame returns it
+	V          [16]byte
+	I          uint16
+	PC         uint16
+	SP         byte
+	DelayTimer byte
+	SoundTimer byte
+	Cycles     uint64
+}
+
+// Snapshot returns a consistent copy of the display, registers and timers.
+// It is safe to call from another goroutine while Step, StepInfo or
+// RunFrame run, e.g. to render, and never sees an instruction or frame half
+// done. Trace and watch callbacks run mid-instruction and must not call it;
+// the Sound, the audio, sound state and frame callbacks and the Display are
+// called after the lock is released and may.
+func (c *chip8) Snapshot() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return State{
+		Frame:      c.Frame(),
+		V:          c.V,
+		I:          c.I,
+		PC:         c.PC,
+		SP:         c.SP,
+		DelayTimer: c.delayTimer,
+		SoundTimer: c.soundTimer,
+		Cycles:     c.cycles,
+	}
+}
+
+// Pause freezes emulation, instructions and timers alike, until Resume. It
+// is safe to call while Run is running on another goroutine.
+func (c *chip8) Pause() {
+	atomic.StoreInt32(&c.paused, 1)
+}
+
+// Resume continues emulation after Pause.
+func (c *chip8) Resume() {
+	atomic.StoreInt32(&c.paused, 0)
+}
+
+// Paused reports whether emulation is paused.
+func (c *chip8) Paused() bool {
+	return atomic.LoadInt32(&c.paused) == 1
+}
+
+// ClockSpeed returns how many instructions are executed per second.
+func (c *chip8) ClockSpeed() int {
+	return c.clockSpeed
+}
+
+// SetClockSpeed sets how many instructions Run executes per second. The
+// fraction of an instruction left over each frame carries over to the next,
+// so speeds below 60 skip frames: 30 runs one instruction every other frame.
+func (c *chip8) SetClockSpeed(hz int) {
+	c.clockSpeed = hz
+}
+
+// SpeedMultiplier returns the factor set by SetSpeedMultiplier.
+func (c *chip8) SpeedMultiplier() float64 {
+	return c.speed
+}
+
+// SetSpeedMultiplier scales the instructions every frame executes by m,
+// clamped to MinSpeedMultiplier-MaxSpeedMultiplier, for fast-forwarding
+// (m > 1) or slow motion (m < 1) without changing ClockSpeed. Frames and the
+// timers still run at 60Hz, so delay loops keep their length while the code
+// between them speeds up or slows down. A NaN m is ignored.
+func (c *chip8) SetSpeedMultiplier(m float64) {
+	if math.IsNaN(m) {
+		return
+	}
+	if m < MinSpeedMultiplier {
+		m = MinSpeedMultiplier
+	}
+	if m > MaxSpeedMultiplier {
+		m = MaxSpeedMultiplier
+	}
+	c.speed = m
+}
+
+// cyclesPerFrame is how many instructions the next frame executes at the
+// clock speed and speed multiplier. What is left of a whole instruction is
+// carried over to later frames, so 1.5 instructions a frame runs 1 and 2 in
+// turn and 0.5 one every other frame.
+func (c *chip8) cyclesPerFrame() int {
+	c.cycleCarry += float64(c.clockSpeed) * c.speed / 60
+	// Allow for rounding, so ten frames of 0.1 make a whole instruction
+	n := int(c.cycleCarry + 1e-9)
+	c.cycleCarry -= float64(n)
+	return n
+}
+
+// RunCycles executes n instructions back to back without sleeping or
+// ticking the timers, stopping early if one fails.
+func (c *chip8) RunCycles(n int) error {
+	defer c.notifySound()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < n; i++ {
+		_, err := c.stepInfo()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// StepResult describes the instruction a step executed.
+type StepResult struct {
+	Opcode   uint16
+	PCBefore uint16 // Address the instruction was fetched from
+	PCAfter  uint16 // Address of the next instruction
+	Drew     bool   // The instruction was a Dxyn draw
+	Skipped  bool   // A conditional skip was taken
+}
+
+// Step executes one instruction in three phases: Fetch, Decode and Execute.
+func (c *chip8) Step() error {
+	_, err := c.StepInfo()
+	return err
+}
+
+// SafeStep is Step for frontends that must not crash: a panic while
+// stepping, from an edge case the interpreter fails to check, is recovered
+// and returned as a *PanicError with PC put back on the instruction. The
+// machine may be left half way through that instruction.
+func (c *chip8) SafeStep() (err error) {
+	pc := c.PC
+	defer func() {
+		if r := recover(); r != nil {
+			c.PC = pc
+			var op uint16
+			if int(pc) < len(c.memory) {
+				op = uint16(c.memory[pc]) << 8
+			}
+			if int(pc)+1 < len(c.memory) {
+				op |= uint16(c.memory[pc+1])
+			}
+			err = &PanicError{Value: r, Opcode: op, PC: pc}
+		}
+	}()
+	return c.Step()
+}
+
+// StepInfo executes one instruction like Step and reports what it did. On
+// error PCAfter equals PCBefore, as PC is left on the failed instruction.
+func (c *chip8) StepInfo() (StepResult, error) {
+	defer c.notifySound()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stepInfo()
+}
+
+// stepInfo is StepInfo with mu already held.
+func (c *chip8) stepInfo() (StepResult, error) {
+	if len(c.replay) > 0 {
+		c.replayInput()
+	}
+	if c.history.snaps != nil {
+		c.pushHistory()
+	}
+	pc := c.PC
+	c.skipped = false
+	opcode, err := c.Fetch()
+	if err == nil {
+		if c.trace != nil {
+			c.trace(pc, opcode, c.V)
+		}
+		err = c.Execute(Decode(opcode))
+	}
+	// 00FD ends the program by returning ErrExit, yet it did execute
+	if c.EnableStats && (err == nil || err == ErrExit) {
+		c.countOpcode(opcode)
+	}
+	if err != nil {
+		// Leave PC on the instruction that failed
+		c.PC = pc
+		c.logger.Printf("Exec opcode error: %s", err)
+		if c.history.snaps != nil {
+			c.dropHistory()
+		}
+	} else {
+		c.cycles++
+	}
+	return StepResult{
+		Opcode:   opcode,
+		PCBefore: pc,
+		PCAfter:  c.PC,
+		Drew:     err == nil && opcode&0xF000 == 0xD000,
+		Skipped:  c.skipped,
+	}, err
+}
+
+// Push puts addr on the stack, returning ErrStackOverflow if all 16 cells
+// are in use.
 func (c *chip8) Push(addr uint16) error {
-	c.SP++
+	if int(c.SP) == len(c.stack) {
+		return ErrStackOverflow
+	}
 	c.stack[c.SP] = addr
+	c.SP++
 
 	return nil
 }
 
-func (c *chip8) FetchInstruction() uint16 {
+// FetchInstruction is the original name of Fetch.
+func (c *chip8) FetchInstruction() (uint16, error) {
+	return c.Fetch()
+}
+
+// Fetch reads the opcode at PC: the fetch phase of Step. Opcodes are stored
+// big-endian, high byte first, as in every normal ROM. An instruction that
+// runs off the end of memory, from PC on its last byte or beyond, is an
+// *AddressError and leaves PC alone.
+func (c *chip8) Fetch() (uint16, error) {
+	if err := c.outOfRange(int(c.PC), 2); err != nil {
+		err.PC = c.PC
+		return 0, err
+	}
 	opCode := uint16(c.memory[c.PC])<<8 | uint16(c.memory[c.PC+1])
-	//c.PC += 2 // TODO: Deside to inc PC here or in each opcode execution
-	return opCode
+	// PC always moves past the fetched instruction here, so opcodes only
+	// touch it to jump, call, return or skip the next instruction.
+	c.PC += 2
+	return opCode, nil
+}
+
+// rplCount is how many registers Fx75/Fx85 transfer for x.
+func rplCount(x uint8) int {
+	if x > 7 {
+		return 8
+	}
+	return int(x) + 1
+}
+
+// skipNext moves PC past the next instruction, which is four bytes long if
+// it is the XO-CHIP F000 NNNN long load.
+func (c *chip8) skipNext() {
+	c.skipped = true
+	// Past the end there is nothing to peek at; the next fetch reports it
+	if int(c.PC)+1 < len(c.memory) && c.memory[c.PC] == 0xF0 && c.memory[c.PC+1] == 0x00 {
+		c.PC += 2
+	}
+	c.PC += 2
 }
 
+// setWithFlag stores an 8xy4-8xyE result in Vx and its flag in VF. The
+// flag is written last, so it wins when x is VF, unless
+// Quirks.FlagBeforeResult says otherwise.
+func (c *chip8) setWithFlag(x uint8, result, flag byte) {
+	if c.Quirks.FlagBeforeResult {
+		c.V[0xF] = flag
+		c.V[x] = result
+		return
+	}
+	c.V[x] = result
+	c.V[0xF] = flag
+}
+
+// checkJump returns an *AddressError if a jump or call to addr would not land
+// on a whole instruction, which on machines with less than 4KB of memory not
+// every 12-bit address does.
+func (c *chip8) checkJump(addr uint16) error {
+	if int(addr) > len(c.memory)-2 {
+		return &AddressError{Addr: int(addr), PC: c.PC - 2}
+	}
+	return nil
+}
+
+// outOfRange returns an *AddressError for the instruction just fetched if
+// the n bytes from addr do not all lie in memory, or nil if they do. The
+// error reports the first address of the access past the end of memory.
+func (c *chip8) outOfRange(addr, n int) *AddressError {
+	if addr+n <= len(c.memory) {
+		return nil
+	}
+	if addr < len(c.memory) {
+		addr = len(c.memory)
+	}
+	return &AddressError{Addr: addr, PC: c.PC - 2}
+}
+
+// unknownOpcode reports op as unimplemented at the address it was fetched
+// from, which is one instruction behind the already advanced PC. With
+// SkipUnknownOpcodes it is only logged and op runs as a no-op.
+func (c *chip8) unknownOpcode(op uint16) error {
+	err := &UnknownOpcodeError{Opcode: op, PC: c.PC - 2}
+	if c.SkipUnknownOpcodes {
+		c.logger.Printf("Skipping: %s", err)
+		return nil
+	}
+	return err
+}
+
+// ExecuteOpcode decodes and executes op, returning it along with any error.
 func (c *chip8) ExecuteOpcode(op uint16) (uint16, error) {
-	log.Printf("%04X", op)
-	switch op & 0xF000 {
-	case 0x0000: // 0nnn
-		switch op {
-		case 0x00E0: // CLS
+	return op, c.Execute(Decode(op))
+}
+
+// Execute runs a decoded instruction: the execute phase of Step. PC must
+// already point past the instruction, as Fetch leaves it. Op only selects
+// the instruction family by its top nibble and names it in errors; the
+// operands come from the decoded fields.
+func (c *chip8) Execute(in Instruction) error {
+	op := in.Op
+	// Switching on the top nibble gives the compiler dense cases 0-F, which
+	// it turns into a jump table rather than a chain of comparisons.
+	switch op >> 12 {
+	case 0x0: // 0nnn
+		switch in.NNN {
+		case 0x0E0: // 00E0 - CLS
 			// Clear the display
 			c.clearDisplay()
-			c.PC += 2
 			break
-		case 0x00EE: // RET
+		case 0x0FE: // 00FE - LOW (SUPER-CHIP)
+			// Switch to 64x32 low resolution and clear the display.
+			c.setHires(false)
+			break
+		case 0x0FF: // 00FF - HIGH (SUPER-CHIP)
+			// Switch to 128x64 high resolution and clear the display.
+			c.setHires(true)
+			break
+		case 0x0FD: // 00FD - EXIT (SUPER-CHIP)
+			// Stop the interpreter. Like any failing instruction it leaves
+			// PC on the 00FD, so stepping again exits again.
+			return ErrExit
+		case 0x0EE: // 00EE - RET
 			// Return from a subroutine.
 			// The interpreter sets the program counter to the address at the
 			// top of the stack, then subtracts 1 from the stack pointer.
-			c.PC = c.stack[c.SP]
+			// SP points at the next free cell, so the top is stack[SP-1].
+			if c.SP == 0 {
+				return &StackError{Err: ErrStackUnderflow, PC: c.PC - 2}
+			}
 			c.SP--
-			c.PC += 2
+			c.PC = c.stack[c.SP]
+			break
+		default: // 0nnn - SYS addr
+			// Jump to a machine code routine at nnn.
+			// This instruction is only used on the old computers on which Chip-8
+			// was originally implemented. It is ignored by modern interpreters.
+			// 0000 past the end of the ROM is zeroed memory, not a SYS call.
+			if in.NNN == 0 && c.romEnd != 0 && int(c.PC-2) >= c.romEnd {
+				return ErrProgramEnd
+			}
+			if !c.IgnoreSysCalls {
+				return c.unknownOpcode(op)
+			}
+			c.logger.Printf("Ignoring SYS 0x%03X at 0x%04X", in.NNN, c.PC-2)
 			break
-		default:
-			return op, fmt.Errorf("Unknown opcode: 0x%04X", op)
 		}
-	case 0x1000: // 1nnn - JP addr
+	case 0x1: // 1nnn - JP addr
 		// Jump to location nnn.
 		// The interpreter sets the program counter to nnn.
-		c.PC = op & 0x0FFF
+		if c.HaltOnInfiniteLoop && in.NNN == c.PC-2 {
+			return ErrHalt
+		}
+		if err := c.checkJump(in.NNN); err != nil {
+			return err
+		}
+		c.PC = in.NNN
 		break
-	case 0x2000: // 2nnn - CALL addr
+	case 0x2: // 2nnn - CALL addr
 		// Call subroutine at nnn.
 		// The interpreter increments the stack pointer, then puts the current
 		// PC on the top of the stack. The PC is then set to nnn.
-		c.Push(c.PC)
-		c.PC = op & 0x0FFF
+		if err := c.checkJump(in.NNN); err != nil {
+			return err
+		}
+		if err := c.Push(c.PC); err != nil {
+			return &StackError{Err: err, PC: c.PC - 2}
+		}
+		c.PC = in.NNN
 		break
-	case 0x3000: //3xkk - SE Vx, byte
+	case 0x3: //3xkk - SE Vx, byte
 		// Skip next instruction if Vx = kk.
 		// The interpreter compares register Vx to kk, and if they are equal,
 		// increments the program counter by 2.
-		kk := byte(op)
-		x := (op & 0x0F00) >> 8
-
-		c.PC += 2
+		kk := in.KK
+		x := in.X
 
 		if kk == c.V[x] {
-			c.PC += 2
-			break
+			c.skipNext()
 		}
 		break
-	case 0x4000: // 4xkk - SNE Vx, byte
+	case 0x4: // 4xkk - SNE Vx, byte
 		// Skip next instruction if Vx != kk.
 		// The interpreter compares register Vx to kk, and if they are not
 		// equal, increments the program counter by 2.
-		kk := byte(op)
-		x := (op & 0x0F00) >> 8
-
-		c.PC += 2
+		kk := in.KK
+		x := in.X
 
 		if kk != c.V[x] {
-			c.PC += 2
-			break
+			c.skipNext()
 		}
 		break
-	case 0x5000: // 5xy0 - SE Vx, Vy
+	case 0x5: // 5xy0 - SE Vx, Vy
 		// 	Skip next instruction if Vx = Vy.
 		// The interpreter compares register Vx to register Vy, and if they are equal, increments the program counter by 2.
-		// TODO: add default throwing an error if any of the last 4 bits are high
-		x := (op & 0x0F00) >> 8
-		y := (op & 0x00F0) >> 4
-
-		c.PC += 2
+		// The low nibble should be 0; StrictDecode rejects anything else.
+		if c.StrictDecode && in.N != 0 {
+			return c.unknownOpcode(op)
+		}
+		x := in.X
+		y := in.Y
 
 		if c.V[x] == c.V[y] {
-			c.PC += 2
-			break
+			c.skipNext()
 		}
 		break
-	case 0x6000: // 6xkk - LD Vx, byte
+	case 0x6: // 6xkk - LD Vx, byte
 		// Set Vx = kk.
 		// The interpreter puts the value kk into register Vx.
-		kk := byte(op)
-		x := (op & 0x0F00) >> 8
+		kk := in.KK
+		x := in.X
 
 		c.V[x] = kk
-
-		c.PC += 2
 		break
-	case 0x7000: // 7xkk - ADD Vx, byte
+	case 0x7: // 7xkk - ADD Vx, byte
 		// Set Vx = Vx + kk.
 		// Adds the value kk to the value of register Vx, then stores the result in Vx.
-		kk := byte(op)
-		x := (op & 0x0F00) >> 8
+		// The sum wraps at 0xFF and, unlike 8xy4, VF is left alone: there is no
+		// carry flag for 7xkk, and ROMs adding to VF itself rely on that.
+		kk := in.KK
+		x := in.X
 
 		c.V[x] += kk
-
-		c.PC += 2
 		break
-	case 0x8000: // 8xyn
-		x := (op & 0x0F00) >> 8
-		y := (op & 0x00F0) >> 4
-		switch op & 0x000F {
+	case 0x8: // 8xyn
+		x := in.X
+		y := in.Y
+		switch in.N {
 		case 0x0000: // 8xy0 - LD Vx, Vy
 			// Set Vx = Vy.
 			// Stores the value of register Vy in register Vx.
 			c.V[x] = c.V[y]
-			c.PC += 2
 			break
 		case 0x0001: // 8xy1 - OR Vx, Vy
 			// Set Vx = Vx OR Vy.
@@ -232,8 +908,9 @@ func (c *chip8) ExecuteOpcode(op uint16) (uint16, error) {
 			// from two values, and if either bit is 1, then the same bit in
 			// the result is also 1. Otherwise, it is 0.
 			c.V[x] |= c.V[y]
-
-			c.PC += 2
+			if c.Quirks.LogicOpsResetVF {
+				c.V[0xF] = 0
+			}
 			break
 		case 0x0002: // 8xy2 - AND Vx, Vy
 			// Set Vx = Vx AND Vy.
@@ -242,8 +919,9 @@ func (c *chip8) ExecuteOpcode(op uint16) (uint16, error) {
 			// from two values, and if both bits are 1, then the same bit in
 			// the result is also 1. Otherwise, it is 0.
 			c.V[x] &= c.V[y]
-
-			c.PC += 2
+			if c.Quirks.LogicOpsResetVF {
+				c.V[0xF] = 0
+			}
 			break
 		case 0x0003: // 8xy3 - XOR Vx, Vy
 			// Set Vx = Vx XOR Vy.
@@ -253,259 +931,381 @@ func (c *chip8) ExecuteOpcode(op uint16) (uint16, error) {
 			// the same, then the corresponding bit in the result is set to 1.
 			// Otherwise, it is 0.
 			c.V[x] ^= c.V[y]
-
-			c.PC += 2
+			if c.Quirks.LogicOpsResetVF {
+				c.V[0xF] = 0
+			}
 			break
 		case 0x0004: // 8xy4 - ADD Vx, Vy
 			// Set Vx = Vx + Vy, set VF = carry.
 			// The values of Vx and Vy are added together. If the result is
 			// greater than 8 bits (i.e., > 255,) VF is set to 1, otherwise 0.
 			// Only the lowest 8 bits of the result are kept, and stored in Vx.
+			// The carry is written last, so it wins when x or y is VF.
 			sum := uint16(c.V[x]) + uint16(c.V[y])
-
-			c.V[x] += c.V[y]
-
+			var carry byte
 			if sum > 0xFF {
-				c.V[0xF] = 0x01
-			} else {
-				c.V[0xF] = 0x00
+				carry = 0x01
 			}
-			c.PC += 2
+			c.setWithFlag(x, byte(sum), carry)
 			break
 		case 0x0005: // 8xy5 - SUB Vx, Vy
 			// Set Vx = Vx - Vy, set VF = NOT borrow.
 			// If Vx > Vy, then VF is set to 1, otherwise 0. Then Vy is
 			// subtracted from Vx, and the results stored in Vx.
+			var notBorrow byte
 			if c.V[x] > c.V[y] {
-				c.V[0xF] = 0x01
-			} else {
-				c.V[0xF] = 0x00
+				notBorrow = 0x01
 			}
-			c.V[x] -= c.V[y]
-
-			c.PC += 2
+			c.setWithFlag(x, c.V[x]-c.V[y], notBorrow)
 			break
 		case 0x0006: // 8xy6 - SHR Vx {, Vy}
 			// Set Vx = Vx SHR 1.
 			// If the least-significant bit of Vx is 1, then VF is set to 1,
 			// otherwise 0. Then Vx is divided by 2.
-			if (c.V[x] & 0x1) == 0x01 {
-				c.V[0xF] = 0x01
-			} else {
-				c.V[0xF] = 0x00
+			v := c.V[x]
+			if c.Quirks.ShiftUsesVy {
+				v = c.V[y]
 			}
-			c.V[x] /= 2
-			// c.V[x] = c.V[x] >> 1
-
-			c.PC += 2
+			c.setWithFlag(x, v>>1, v&0x1)
 			break
 		case 0x0007: // 8xy7 - SUBN Vx, Vy
 			// Set Vx = Vy - Vx, set VF = NOT borrow.
 			// If Vy > Vx, then VF is set to 1, otherwise 0. Then Vx is
 			// subtracted from Vy, and the results stored in Vx.
+			var notBorrow byte
 			if c.V[y] > c.V[x] {
-				c.V[0xF] = 0x01
-			} else {
-				c.V[0xF] = 0x00
+				notBorrow = 0x01
 			}
-			c.V[x] = c.V[y] - c.V[x]
-
-			c.PC += 2
+			c.setWithFlag(x, c.V[y]-c.V[x], notBorrow)
 			break
 		case 0x000E: // 8xyE - SHL Vx {, Vy}
 			// Set Vx = Vx SHL 1.
 			// If the most-significant bit of Vx is 1, then VF is set to 1,
 			// otherwise to 0. Then Vx is multiplied by 2.
-			if (c.V[x] & 0x80) == 0x80 {
-				c.V[0xF] = 0x01
-			} else {
-				c.V[0xF] = 0x00
+			v := c.V[x]
+			if c.Quirks.ShiftUsesVy {
+				v = c.V[y]
 			}
-			c.V[x] = c.V[x] << 1
-
-			c.PC += 2
+			c.setWithFlag(x, v<<1, v>>7)
 			break
+		default: // 8xy8-8xyD, 8xyF are undefined and do nothing
+			if c.StrictDecode {
+				return c.unknownOpcode(op)
+			}
 		}
-	case 0x9000: // 9xy0 - SNE Vx, Vy
+	case 0x9: // 9xy0 - SNE Vx, Vy
 		// Skip next instruction if Vx != Vy.
 		// The values of Vx and Vy are compared, and if they are not equal, the
 		// program counter is increased by 2.
-		x := (op & 0x0F00) >> 8
-		y := (op & 0x00F0) >> 4
+		if c.StrictDecode && in.N != 0 {
+			return c.unknownOpcode(op)
+		}
+		x := in.X
+		y := in.Y
 
 		if c.V[x] != c.V[y] {
-			c.PC += 2
+			c.skipNext()
 		}
-
-		c.PC += 2
 		break
-	case 0xA000: // Annn - LD I, addr
+	case 0xA: // Annn - LD I, addr
 		// Set I = nnn.
 		// The value of register I is set to nnn.
-		c.I = op & 0x0FFF
-		c.PC += 2
+		c.I = in.NNN
 		break
-	case 0xB000: // Bnnn - JP V0, addr
+	case 0xB: // Bnnn - JP V0, addr
 		// Jump to location nnn + V0.
 		// The program counter is set to nnn plus the value of V0.
-		c.PC = (op & 0x0FFF) + uint16(c.V[0])
+		// A target past the last whole instruction in memory is handled as
+		// Quirks.JumpOverflow says, so the next fetch always stays in range.
+		r := c.V[0]
+		if c.Quirks.JumpUsesVx {
+			r = c.V[in.X]
+		}
+		target := int(in.NNN) + int(r)
+		if last := len(c.memory) - 2; target > last {
+			switch c.Quirks.JumpOverflow {
+			case JumpClamp:
+				target = last
+			case JumpError:
+				return &AddressError{Addr: target, PC: c.PC - 2}
+			default:
+				// The last byte is no whole instruction either, so a target
+				// wrapping onto it moves on to the start of memory too
+				if target %= len(c.memory); target > last {
+					target = 0
+				}
+			}
+		}
+		c.PC = uint16(target)
 		break
-	case 0xC000: // Cxkk - RND Vx, byte
+	case 0xC: // Cxkk - RND Vx, byte
 		// Set Vx = random byte AND kk.
 		// The interpreter generates a random number from 0 to 255, which is
 		// then ANDed with the value kk. The results are stored in Vx.
-		x := (op & 0x0F00) >> 8
-		kk := byte(op)
-		rnd := byte(rand.Intn(256))
+		x := in.X
+		kk := in.KK
+		rnd := c.random()
 
 		c.V[x] = rnd & kk
-
-		c.PC += 2
 		break
-	case 0xD000: // Dxyn - DRW Vx, Vy, nibble
+	case 0xD: // Dxyn - DRW Vx, Vy, nibble
 		// Display n-byte sprite starting at memory location I at (Vx, Vy), set
 		// VF = collision.
 		// The interpreter reads n bytes from memory, starting at the address
 		// stored in I. These bytes are then displayed as sprites on screen at
 		// coordinates (Vx, Vy). Sprites are XORed onto the existing screen. If
 		// this causes any pixels to be erased, VF is set to 1, otherwise it is
-		// set to 0. If the sprite is positioned so part of it is outside the
-		// coordinates of the display, it wraps around to the opposite side of
-		// the screen.
-		x := (op & 0x0F00) >> 8
-		y := (op & 0x00F0) >> 4
-		n := (op & 0x000F)
-		c.V[0xF] = 0
+		// set to 0. The origin always wraps around the screen; the parts of
+		// the sprite past the edges are clipped, or wrap around to the
+		// opposite side of the screen with the WrapSprites quirk.
+		// XO-CHIP: every selected plane gets its own n-byte sprite, read
+		// consecutively from I (plane 0 first). I may point anywhere,
+		// including at the fonts below 0x200 as Fx29 and Fx30 do.
+		x := in.X
+		y := in.Y
+		n := uint16(in.N)
 		j := uint16(0)
 		i := uint16(0)
+		addr := c.I
+		w, h := uint16(c.width()), uint16(c.height())
+		// Read the origin before VF is touched, as Vx or Vy may be VF
+		originX, originY := uint16(c.V[x])%w, uint16(c.V[y])%h
+		collision := false
+		var region drawRegion
 
-		for j = 0; j < n; j++ {
-			//TODO: remove log
-			//log.Printf("Opcode: %04X loop: %d", op, j)
-			pixel := c.memory[c.I+j]
-			for i = 0; i < 8; i++ {
-				//log.Printf("Opcode: %04X inner loop: %d", op, i)
-				if (pixel & (0x80 >> i)) != 0 {
-					if c.display[(c.V[y] + uint8(j))][c.V[x]+uint8(i)] == 1 {
-						c.V[0xF] = 1
+		for plane := 0; plane < 2; plane++ {
+			if c.planes&(1<<plane) == 0 {
+				continue
+			}
+			for j = 0; j < n; j++ {
+				pixel := c.readMemory(int(addr+j) % len(c.memory))
+				for i = 0; i < 8; i++ {
+					if (pixel & (0x80 >> i)) != 0 {
+						px, py := originX+i, originY+j
+						if px >= w || py >= h {
+							if !c.Quirks.WrapSprites {
+								continue
+							}
+							px, py = px%w, py%h
+						}
+						if c.display.flip(int(px), int(py), plane) {
+							collision = true
+						}
+						region.add(px, py)
 					}
-					c.display[(c.V[y] + uint8(j))][c.V[x]+uint8(i)] ^= 1
 				}
 			}
+			addr += n
 		}
-		c.PC += 2
+		// Any erased pixel counts, across every row, plane and wrap
+		c.V[0xF] = 0
+		if collision {
+			c.V[0xF] = 1
+		}
+		c.collision = collision
+		c.lastDraw = region
+		c.dirty = true
+		c.vblank = c.Quirks.DisplayWait
 		break
-	case 0xE000:
-		x := (op & 0x0F00) >> 8
-		switch op & 0x00FF {
+	case 0xE:
+		x := in.X
+		switch in.KK {
 		case 0x9E: // Ex9E - SKP Vx
 			// Skip next instruction if key with the value of Vx is pressed.
 			// Checks the keyboard, and if the key corresponding to the value of Vx
 			// is currently in the down position, PC is increased by 2.
-			if c.keypad[c.V[x]] == 1 {
-				c.PC += 2
+			// Only the low nibble names a key.
+			if c.keyHeld(c.V[x] & 0x0F) {
+				c.skipNext()
 			}
-			c.PC += 2
 			break
 		case 0xA1: // ExA1 - SKNP Vx
 			// Skip next instruction if key with the value of Vx is not pressed.
 			// Checks the keyboard, and if the key corresponding to the value of Vx
 			// is currently in the up position, PC is increased by 2.
-			if c.keypad[c.V[x]] == 0 {
-				c.PC += 2
+			if !c.keyHeld(c.V[x] & 0x0F) {
+				c.skipNext()
 			}
-			c.PC += 2
 			break
 		default:
-			return op, fmt.Errorf("Unknown opcode: 0x%04X", op)
+			return c.unknownOpcode(op)
 		}
-	case 0xF000:
-		x := (op & 0x0F00) >> 8
-		switch op & 0x00FF {
+	case 0xF:
+		x := in.X
+		switch in.KK {
+		case 0x00: // F000 NNNN - LD I, long addr (XO-CHIP)
+			// Set I = NNNN.
+			// The 16-bit address is read from the word following the
+			// instruction, so PC advances by 4 in total.
+			if x != 0 {
+				return c.unknownOpcode(op)
+			}
+			if err := c.outOfRange(int(c.PC), 2); err != nil {
+				return err
+			}
+			addr := uint16(c.memory[c.PC])<<8 | uint16(c.memory[c.PC+1])
+			if int(addr) >= len(c.memory) {
+				return &AddressError{Addr: int(addr), PC: c.PC - 2}
+			}
+			c.I = addr
+			c.PC += 2
+			break
+		case 0x01: // FN01 - PLANE n (XO-CHIP)
+			// Select drawing planes.
+			// Bit 0 of n selects plane 0 and bit 1 selects plane 1. DRW and
+			// CLS only affect the selected planes.
+			c.planes = byte(x) & 0x3
+			break
+		case 0x02: // F002 - AUDIO (XO-CHIP)
+			// Load the audio pattern buffer.
+			// The 16 bytes starting at I become the 128-bit pattern played
+			// while the sound timer is nonzero.
+			if x != 0 {
+				return c.unknownOpcode(op)
+			}
+			if err := c.outOfRange(int(c.I), len(c.audioPattern)); err != nil {
+				return err
+			}
+			for i := range c.audioPattern {
+				c.audioPattern[i] = c.readMemory(int(c.I) + i)
+			}
+			c.hasPattern = true
+			if p, ok := c.sound.(PatternSound); ok {
+				p.SetPattern(c.audioPattern)
+			}
+			break
 		case 0x07: // Fx07 - LD Vx, DT
 			// Set Vx = delay timer value.
 			// The value of DT is placed into Vx.
 			c.V[x] = c.delayTimer
-
-			c.PC += 2
 			break
 		case 0x0A: // Fx0A - LD Vx, K
 			// Wait for a key press, store the value of the key in Vx.
 			// All execution stops until a key is pressed, then the value
 			// of that key is stored in Vx.
+			// Rather than blocking, the instruction repeats until a key is
+			// down so timers and input keep being serviced.
 			pressed := false
-			for !pressed {
-				for i := 0; i < 16; i++ {
-					if c.keypad[i] == 1 {
-						c.V[x] = byte(i)
-						pressed = true
-					}
+			for i := uint8(0); i < 16; i++ {
+				if c.keyHeld(i) {
+					c.V[x] = i
+					// A buffered tap is used up once it has been read
+					c.latched &^= 1 << i
+					pressed = true
+					break
 				}
 			}
-			c.PC += 2
+			if !pressed {
+				c.PC -= 2
+			}
 			break
 		case 0x15: // Fx15 - LD DT, Vx
 			// Set delay timer = Vx.
 			// DT is set equal to the value of Vx.
-			c.delayTimer = c.V[x]
-			c.PC += 2
+			c.SetDelayTimer(c.V[x])
 			break
 		case 0x18: // Fx18 - LD ST, Vx
 			// Set sound timer = Vx.
 			// ST is set equal to the value of Vx.
-			c.soundTimer = c.V[x]
-			c.PC += 2
+			c.setSoundTimer(c.V[x])
 			break
 		case 0x1E: // Fx1E - ADD I, Vx
 			// Set I = I + Vx.
 			// The values of I and Vx are added, and the results are stored in I.
-			c.I += uint16(c.V[x])
-			c.PC += 2
+			// I wraps around at the end of memory rather than pointing past it.
+			// With IndexOverflowSetsVF, VF is set to 1 if the sum leaves the
+			// 12-bit address space and 0 otherwise, as on the Amiga.
+			sum := int(c.I) + int(c.V[x])
+			c.I = uint16(sum % len(c.memory))
+			if c.Quirks.IndexOverflowSetsVF {
+				c.V[0xF] = 0
+				if sum > 0x0FFF {
+					c.V[0xF] = 1
+				}
+			}
 			break
 		case 0x29: // Fx29 - LD F, Vx
 			// Set I = location of sprite for digit Vx.
 			// The value of I is set to the location for the hexadecimal sprite
 			// corresponding to the value of Vx.
-			c.I += uint16(c.V[x]) * uint16(0x05)
-			c.PC += 2
+			// Only the low nibble names a digit.
+			c.I = c.fontBase + uint16(c.V[x]&0x0F)*FontHeight
+			break
+		case 0x30: // Fx30 - LD HF, Vx (SUPER-CHIP)
+			// Set I = location of the large 8x10 sprite for digit Vx.
+			c.I = c.largeFontBase + uint16(c.V[x]&0x0F)*LargeFontHeight
 			break
 		case 0x33: // Fx33 - LD B, Vx
 			// Store BCD representation of Vx in memory locations I, I+1, and I+2.
 			// The interpreter takes the decimal value of Vx, and places the
 			// hundreds digit in memory at location in I, the tens digit at location
 			// I+1, and the ones digit at location I+2.
-			c.memory[c.I] = c.V[x] / 100
-			c.memory[c.I+1] = (c.V[x] / 10) % 10
-			c.memory[c.I+2] = (c.V[x] % 100) % 10
-
-			c.PC += 2
+			// BCDWidth digits are stored, checking first that all of them fit
+			// so nothing is written when they run past the end of memory.
+			width := c.BCDWidth
+			if width < 3 {
+				width = 3
+			}
+			if err := c.outOfRange(int(c.I), width); err != nil {
+				return err
+			}
+			digits := [3]byte{c.V[x] / 100, (c.V[x] / 10) % 10, (c.V[x] % 100) % 10}
+			for i := 0; i < width; i++ {
+				var d byte // Leading zero
+				if j := i - (width - 3); j >= 0 {
+					d = digits[j]
+				}
+				if err := c.writeMemory(c.I+uint16(i), d); err != nil {
+					return err
+				}
+			}
 			break
 		case 0x55: // Fx55 - LD [I], Vx
 			// Store registers V0 through Vx in memory starting at location I.
 			// The interpreter copies the values of registers V0 through Vx into
 			// memory, starting at the address in I.
+			// The whole range is checked first so nothing is written when it
+			// runs past the end of memory.
+			if err := c.outOfRange(int(c.I), int(x)+1); err != nil {
+				return err
+			}
 			var i uint16
-			for i = 0; i <= x; i++ {
-				c.memory[c.I+i] = c.V[i]
+			for i = 0; i <= uint16(x); i++ {
+				if err := c.writeMemory(c.I+i, c.V[i]); err != nil {
+					return err
+				}
 			}
-			c.PC += 2
+			c.I += c.Quirks.LoadStoreIncrement.advance(x)
 			break
 		case 0x65: // Fx65 - LD Vx, [I]
 			// Read registers V0 through Vx from memory starting at location I.
 			// The interpreter reads values from memory starting at location I into
 			// registers V0 through Vx.
+			if err := c.outOfRange(int(c.I), int(x)+1); err != nil {
+				return err
+			}
 			var i uint16
-			for i = 0; i <= x; i++ {
-				c.V[i] = c.memory[c.I+i]
+			for i = 0; i <= uint16(x); i++ {
+				c.V[i] = c.readMemory(int(c.I + i))
 			}
-			c.PC += 2
+			c.I += c.Quirks.LoadStoreIncrement.advance(x)
+			break
+		case 0x75: // Fx75 - LD R, Vx (SUPER-CHIP)
+			// Store V0..Vx in the RPL user flags.
+			// The HP-48 only has 8 flags, so x is limited to 7.
+			copy(c.rplFlags[:], c.V[:rplCount(x)])
+			break
+		case 0x85: // Fx85 - LD Vx, R (SUPER-CHIP)
+			// Read V0..Vx from the RPL user flags.
+			// The HP-48 only has 8 flags, so x is limited to 7.
+			copy(c.V[:rplCount(x)], c.rplFlags[:])
 			break
 		default:
-			return op, fmt.Errorf("Unknown opcode: 0x%04X", op)
+			return c.unknownOpcode(op)
 		}
 	default:
-		return op, fmt.Errorf("Unknown opcode: 0x%04X", op)
+		return c.unknownOpcode(op)
 	}
 
-	return op, nil
+	return nil
 }
@@ -1,15 +1,22 @@
 package interpreter
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"log"
+	"math"
 	"os"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestLoadRom(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	game, err := os.Open("../roms/space_invaders.ch8")
 	if err != nil {
 		log.Panicf("Error opening file: %s", err)
@@ -29,7 +36,7 @@ func TestLoadRom(t *testing.T) {
 }
 
 func TestLoadBytes(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x42, 0x69}
 	chip8.LoadBytes(0x3, testBytes)
 
@@ -45,24 +52,194 @@ func TestLoadBytes(t *testing.T) {
 	}
 }
 
+func TestLoadShortReads(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x42, 0x69, 0x68, 0x67, 0x66}
+
+	n, err := chip8.load(0x200, iotest.OneByteReader(bytes.NewReader(testBytes)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, len(testBytes), n)
+	assert.Equal(t, testBytes, chip8.memory[0x200:0x205])
+}
+
+func TestLoadStopsAtEndOfMemory(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x42, 0x69, 0x68, 0x67}
+
+	n, err := chip8.LoadBytes(0xFFE, testBytes)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []byte{0x42, 0x69}, chip8.memory[0xFFE:])
+}
+
+func TestLoadRomBytes(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	rom := []byte{0x62, 0x69, 0x12, 0x00}
+
+	n, err := chip8.LoadRomBytes(rom)
+
+	assert.NoError(t, err)
+	assert.Equal(t, len(rom), n)
+	assert.Equal(t, rom, chip8.memory[0x200:0x204])
+}
+
+func TestSetEntryPoint(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	assert.Equal(t, uint16(0x200), chip8.EntryPoint())
+	assert.NoError(t, chip8.SetEntryPoint(0x600))
+	rom := []byte{0x60, 0x01, 0x16, 0x00} // LD V0, 1; JP 0x600
+
+	n, err := chip8.LoadRomBytes(rom)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, rom, chip8.memory[0x600:0x604])
+	assert.Equal(t, []byte{0, 0, 0, 0}, chip8.memory[0x200:0x204])
+	assert.Equal(t, uint16(0x600), chip8.PC)
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, byte(1), chip8.V[0])
+
+	chip8.Reset()
+	assert.Equal(t, uint16(0x600), chip8.PC)
+	assert.Equal(t, uint16(0x600), chip8.EntryPoint())
+
+	assert.Error(t, chip8.SetEntryPoint(0x1FF))
+	assert.Error(t, chip8.SetEntryPoint(0xFFF))
+	assert.NoError(t, chip8.SetEntryPoint(0xFFE))
+	assert.Equal(t, uint16(0xFFE), chip8.EntryPoint())
+}
+
+func TestLoadRomBytesTooLarge(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	rom := make([]byte, 0x1000-0x200+1)
+
+	_, err := chip8.LoadRomBytes(rom)
+
+	assert.ErrorIs(t, err, ErrRomTooLarge)
+}
+
+func TestProgramEnd(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	_, err := chip8.LoadRom(bytes.NewReader([]byte{0x60, 0x01, 0x61, 0x02})) // LD V0, 1; LD V1, 2
+
+	assert.NoError(t, err)
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.ErrorIs(t, chip8.Step(), ErrProgramEnd)
+	assert.Equal(t, uint16(0x204), chip8.PC)
+}
+
+func TestProgramEndOnlyPastTheROM(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	_, err := chip8.LoadRomBytes([]byte{0x00, 0x00}) // A real 0000 inside the ROM
+
+	assert.NoError(t, err)
+	err = chip8.Step()
+	var uerr *UnknownOpcodeError
+	assert.ErrorAs(t, err, &uerr)
+	assert.NotErrorIs(t, err, ErrProgramEnd)
+}
+
+func TestLoadRomFS(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	fsys := fstest.MapFS{
+		"roms/test.ch8": &fstest.MapFile{Data: []byte{0x00, 0xE0, 0x12, 0x02}},
+	}
+
+	n, err := chip8.LoadRomFS(fsys, "roms/test.ch8")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []byte{0x00, 0xE0, 0x12, 0x02}, chip8.memory[0x200:0x204])
+
+	_, err = chip8.LoadRomFS(fsys, "roms/missing.ch8")
+	assert.Error(t, err)
+}
+
 /*** INSTRUCTION TESTS ***/
 func TestFetchInstruction(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x42, 0x69, 0x68, 0x67}
 	chip8.LoadBytes(0x200, testBytes)
 
-	got := chip8.FetchInstruction()
+	got, err := chip8.FetchInstruction()
 
+	assert.NoError(t, err)
 	assert.Equal(t, uint16(0x4269), got)
+	assert.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func TestStepAdvancesPC(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x62, 0x69, 0x63, 0x42}
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x204), chip8.PC)
+}
+
+func TestStepSkipNotTaken(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x32, 0x69}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[2] = 0x42
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func TestStepSkipTaken(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x32, 0x69}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[2] = 0x69
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x204), chip8.PC)
+}
+
+func TestStepJump(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x13, 0x00}
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x300), chip8.PC)
+}
+
+func TestStepCallAndReturn(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x23, 0x00})
+	chip8.LoadBytes(0x300, []byte{0x00, 0xEE})
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x300), chip8.PC)
+	assert.Equal(t, uint16(0x202), chip8.stack[chip8.SP-1])
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func TestStepErrorKeepsPC(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x00, 0x69}
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.Error(t, chip8.Step())
+	assert.Equal(t, uint16(0x200), chip8.PC)
 }
 
 func TestCLS(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x00, 0xE0, 0x00, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
 
-	opcode := chip8.FetchInstruction()
-	_, err := chip8.ExecuteOpcode(opcode)
+	opcode, err := chip8.FetchInstruction()
+	assert.NoError(t, err)
+	_, err = chip8.ExecuteOpcode(opcode)
 	if err != nil {
 		t.Errorf("Error: %s", err)
 	}
@@ -71,26 +248,26 @@ func TestCLS(t *testing.T) {
 }
 
 func TestRET(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x00, 0xE0, 0x00, 0xEE, 0x00, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
 
 	// Manually set stack
 	chip8.stack[0] = uint16(0x666)
 	chip8.stack[1] = uint16(0x222)
-	chip8.SP = 1
+	chip8.SP = 2
 
-	chip8.Run()
+	chip8.RunCycles(2)
 
-	assert.Equal(t, uint16(0x224), chip8.PC)
+	assert.Equal(t, uint16(0x222), chip8.PC)
 }
 
 func TestJMP(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x12, 0x04, 0x00, 0x00, 0x00, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	currentOp := uint16(chip8.memory[chip8.PC])<<8 | uint16(chip8.memory[chip8.PC+1])
 
@@ -99,27 +276,27 @@ func TestJMP(t *testing.T) {
 }
 
 func TestCAL(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x22, 0x04, 0x00, 0x00, 0x00, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
 
 	assert.Equal(t, uint8(0x00), chip8.SP)
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	currentOp := uint16(chip8.memory[chip8.PC])<<8 | uint16(chip8.memory[chip8.PC+1])
 
 	assert.Equal(t, uint8(0x01), chip8.SP)
-	assert.Equal(t, uint16(0x200), chip8.stack[chip8.SP])
+	assert.Equal(t, uint16(0x202), chip8.stack[chip8.SP-1])
 	assert.Equal(t, uint16(0x204), chip8.PC)
 	assert.Equal(t, uint16(0x0069), currentOp)
 }
 
 func TestSkipInstruction3xkk(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x32, 0x69, 0x00, 0x00, 0x00, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x69
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	currentOp := uint16(chip8.memory[chip8.PC])<<8 | uint16(chip8.memory[chip8.PC+1])
 
@@ -128,11 +305,11 @@ func TestSkipInstruction3xkk(t *testing.T) {
 }
 
 func TestSkipInstruction4xkk(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x42, 0x69, 0x00, 0x00, 0x00, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x42
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	currentOp := uint16(chip8.memory[chip8.PC])<<8 | uint16(chip8.memory[chip8.PC+1])
 
@@ -141,13 +318,13 @@ func TestSkipInstruction4xkk(t *testing.T) {
 }
 
 func TestSkipInstruction5xy0(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x52, 0x40, 0x00, 0x00, 0x00, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x42
 	chip8.V[4] = 0x42
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	currentOp := uint16(chip8.memory[chip8.PC])<<8 | uint16(chip8.memory[chip8.PC+1])
 
@@ -156,29 +333,56 @@ func TestSkipInstruction5xy0(t *testing.T) {
 }
 
 func TestLoadVx6xkk(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x62, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x69), chip8.V[2])
 }
 
 func TestADD7xkk(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x72, 0x39}
 	chip8.LoadBytes(0x200, testBytes)
 
 	chip8.V[2] = 0x30
 	assert.NotEqual(t, uint8(0x69), chip8.V[2])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x69), chip8.V[2])
 }
 
+func TestADD7xkkWrapsWithoutCarry(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x72, 0x10, 0x73, 0x01} // ADD V2, 0x10; ADD V3, 0x01
+	chip8.LoadBytes(0x200, testBytes)
+
+	for _, vf := range []byte{0x00, 0x01} {
+		chip8.PC = 0x200
+		chip8.V[2], chip8.V[3] = 0xFA, 0xFF
+		chip8.V[0xF] = vf
+
+		assert.NoError(t, chip8.RunCycles(2))
+		assert.Equal(t, uint8(0x0A), chip8.V[2])
+		assert.Equal(t, uint8(0x00), chip8.V[3])
+		assert.Equal(t, vf, chip8.V[0xF], "7xkk must not touch VF")
+	}
+}
+
+func TestADD7xkkToVF(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x7F, 0x02} // ADD VF, 2
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[0xF] = 0xFF
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint8(0x01), chip8.V[0xF])
+}
+
 func TestLoadVxVy8xy0(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x30}
 	chip8.LoadBytes(0x200, testBytes)
 
@@ -186,13 +390,13 @@ func TestLoadVxVy8xy0(t *testing.T) {
 	chip8.V[3] = 0x69
 	assert.NotEqual(t, uint8(0x69), chip8.V[2])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x69), chip8.V[2])
 }
 
 func TestOrVxVy8xy1(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x31}
 	chip8.LoadBytes(0x200, testBytes)
 
@@ -200,13 +404,13 @@ func TestOrVxVy8xy1(t *testing.T) {
 	chip8.V[3] = 0x69
 	assert.NotEqual(t, uint8(0x69), chip8.V[2])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x69), chip8.V[2])
 }
 
 func TestANDVxVy8xy2(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x32}
 	chip8.LoadBytes(0x200, testBytes)
 
@@ -214,67 +418,101 @@ func TestANDVxVy8xy2(t *testing.T) {
 	chip8.V[3] = 0x69
 	assert.NotEqual(t, uint8(0x69), chip8.V[2])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x69), chip8.V[2])
 }
 
 func TestXORVxVy8xy3(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x33}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x41
 	chip8.V[3] = 0x28
 	assert.NotEqual(t, uint8(0x69), chip8.V[2])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x69), chip8.V[2])
 }
 
 func TestADDVxVy8xy4(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x34}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x88
 	chip8.V[3] = 0x99
 	assert.NotEqual(t, uint8(0x01), chip8.V[0xF])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x21), chip8.V[0x2])
 	assert.Equal(t, uint8(0x01), chip8.V[0xF])
 }
 
+func TestADDVxVy8xy4CarryInVF(t *testing.T) {
+	tests := []struct {
+		name   string
+		op     uint16
+		vx, vf byte
+		vf2    byte // VF after the instruction
+	}{
+		{"x is VF, carry", 0x8F24, 0, 0xF0, 0x01},
+		{"x is VF, no carry", 0x8F24, 0, 0x10, 0x00},
+		{"y is VF, carry", 0x82F4, 0xF0, 0x20, 0x01},
+		{"y is VF, no carry", 0x82F4, 0x0F, 0x20, 0x00},
+		{"both VF, carry", 0x8FF4, 0, 0x80, 0x01},
+		{"both VF, no carry", 0x8FF4, 0, 0x7F, 0x00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chip8 := newTestChip8(DefaultMemorySize)
+			chip8.LoadBytes(0x200, []byte{byte(tt.op >> 8), byte(tt.op)})
+			chip8.V[0x2] = 0x20
+			if tt.op>>8&0xF == 0x2 {
+				chip8.V[0x2] = tt.vx
+			}
+			chip8.V[0xF] = tt.vf
+
+			assert.NoError(t, chip8.Step())
+
+			assert.Equal(t, tt.vf2, chip8.V[0xF], "VF holds the carry, not the sum")
+			if tt.op>>8&0xF == 0x2 {
+				assert.Equal(t, tt.vx+tt.vf, chip8.V[0x2])
+			}
+		})
+	}
+}
+
 // TODO: Må dobbeltsjekke utregningen
 func TestSUBVxVy8xy5(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x35}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x99
 	chip8.V[3] = 0x88
 	assert.NotEqual(t, uint8(0x01), chip8.V[0xF])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x01), chip8.V[0xF])
 }
 
 func TestSHRVxVy8xy6(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x36}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x66
 	assert.Equal(t, uint8(0x00), chip8.V[0xF])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x00), chip8.V[0xF])
 	assert.Equal(t, uint8(0x33), chip8.V[2])
 }
 
 func TestSUBNVxVy8xy7(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x37}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x30
@@ -282,34 +520,34 @@ func TestSUBNVxVy8xy7(t *testing.T) {
 	assert.Equal(t, uint8(0x00), chip8.V[0xF])
 	assert.Equal(t, uint8(0x30), chip8.V[2])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x01), chip8.V[0xF])
 	assert.Equal(t, uint8(0x69), chip8.V[2])
 }
 
 func TestSHLVxVy8xyE(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x82, 0x3E}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x92
 	chip8.V[3] = 0x99
 	assert.Equal(t, uint8(0x00), chip8.V[0xF])
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint8(0x01), chip8.V[0xF])
 	assert.Equal(t, uint8(0x24), chip8.V[2])
 }
 
 func TestSNEVxVy9xy0(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0x92, 0x30, 0x00, 0x00, 0x00, 0x69}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[2] = 0x42
 	chip8.V[3] = 0x69
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	currentOp := uint16(chip8.memory[chip8.PC])<<8 | uint16(chip8.memory[chip8.PC+1])
 
@@ -317,22 +555,1024 @@ func TestSNEVxVy9xy0(t *testing.T) {
 }
 
 func TestLoadIAnnn(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0xA6, 0x66}
 	chip8.LoadBytes(0x200, testBytes)
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint16(0x0666), chip8.I)
 }
 
 func TestJumpV0nnnBnnn(t *testing.T) {
-	chip8 := NewChip8()
+	chip8 := newTestChip8(DefaultMemorySize)
 	testBytes := []byte{0xB6, 0x00}
 	chip8.LoadBytes(0x200, testBytes)
 	chip8.V[0] = 0x66
 
-	chip8.Run()
+	chip8.RunCycles(1)
 
 	assert.Equal(t, uint16(0x0666), chip8.PC)
 }
+
+func TestUnknownOpcodeError(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x62, 0x69, 0xE2, 0x00}
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.NoError(t, chip8.Step())
+	err := chip8.Step()
+
+	var opErr *UnknownOpcodeError
+	if assert.True(t, errors.As(err, &opErr)) {
+		assert.Equal(t, uint16(0xE200), opErr.Opcode)
+		assert.Equal(t, uint16(0x202), opErr.PC)
+	}
+}
+
+func TestUnknownXOChipLongForms(t *testing.T) {
+	// F000 and F002 take no register, so any other x is undefined
+	for _, op := range []uint16{0xF100, 0xF302} {
+		chip8 := newTestChip8(DefaultMemorySize)
+		chip8.LoadBytes(0x200, []byte{byte(op >> 8), byte(op)})
+
+		err := chip8.Step()
+
+		var opErr *UnknownOpcodeError
+		if assert.ErrorAs(t, err, &opErr, "%04X", op) {
+			assert.Equal(t, op, opErr.Opcode)
+		}
+		assert.False(t, chip8.hasPattern, "%04X", op)
+	}
+}
+
+func TestRunContextCancel(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x12, 0x00} // JP 0x200
+	chip8.LoadBytes(0x200, testBytes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- chip8.RunContext(ctx)
+	}()
+
+	time.Sleep(3 * TimerPeriod)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("RunContext did not return after cancel")
+	}
+}
+
+// fakeClock advances only when slept on, calling onSleep after each sleep.
+type fakeClock struct {
+	now     time.Time
+	slept   []time.Duration
+	onSleep func()
+}
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func (f *fakeClock) Sleep(d time.Duration) {
+	f.now = f.now.Add(d)
+	f.slept = append(f.slept, d)
+	if f.onSleep != nil {
+		f.onSleep()
+	}
+}
+
+func TestRunContextFakeClock(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+	chip8.SetDelayTimer(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	clk.onSleep = func() {
+		if len(clk.slept) == 2 {
+			cancel()
+		}
+	}
+	chip8.SetClock(clk)
+
+	assert.ErrorIs(t, chip8.RunContext(ctx), context.Canceled)
+	assert.Equal(t, []time.Duration{TimerPeriod, TimerPeriod}, clk.slept)
+	assert.Equal(t, byte(8), chip8.DelayTimer(), "exactly two frames")
+	assert.Equal(t, uint64(2), chip8.Cycles())
+}
+
+func TestSafeStep(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x60, 0x01}) // LD V0, 1
+	assert.NoError(t, chip8.SafeStep())
+	assert.Equal(t, byte(1), chip8.V[0])
+
+	// A trace callback that panics takes the whole step down with it
+	chip8.LoadBytes(0x202, []byte{0x12, 0x00}) // JP 0x200
+	chip8.SetTraceFunc(func(pc, op uint16, v [16]byte) { panic("trace") })
+	var err error
+	assert.NotPanics(t, func() { err = chip8.SafeStep() })
+
+	var perr *PanicError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, uint16(0x202), perr.PC)
+		assert.Equal(t, uint16(0x1200), perr.Opcode)
+		assert.Contains(t, err.Error(), "0x1200 at 0x0202")
+	}
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.NoError(t, chip8.RunCycles(0), "the lock was released")
+}
+
+func TestRunContextSlowHost(t *testing.T) {
+	tests := []struct {
+		name   string
+		stall  time.Duration // Host time the first instruction takes
+		frames uint64        // Frames run by the second sleep
+	}{
+		{"fast host", 0, 2},
+		{"catches up", 50 * time.Millisecond, 5},
+		{"drops frames", time.Second, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chip8 := newTestChip8(DefaultMemorySize)
+			chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+			ctx, cancel := context.WithCancel(context.Background())
+			clk := &fakeClock{now: time.Unix(0, 0)}
+			clk.onSleep = func() {
+				if len(clk.slept) == 2 {
+					cancel()
+				}
+			}
+			chip8.SetClock(clk)
+			chip8.SetTraceFunc(func(pc, opcode uint16, regs [16]byte) {
+				if chip8.Cycles() == 0 {
+					clk.now = clk.now.Add(tt.stall)
+				}
+			})
+
+			assert.ErrorIs(t, chip8.RunContext(ctx), context.Canceled)
+			assert.Equal(t, tt.frames, chip8.Cycles())
+			for _, d := range clk.slept {
+				assert.True(t, d > 0 && d <= TimerPeriod, "slept %s", d)
+			}
+		})
+	}
+}
+
+func TestHaltOnInfiniteLoop(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x12, 0x00} // JP 0x200
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.HaltOnInfiniteLoop = true
+
+	assert.ErrorIs(t, chip8.Step(), ErrHalt)
+	assert.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestExitOpcode(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetClockSpeed(600)
+	// ADD V0, 1; EXIT; JP 0x200
+	chip8.LoadBytes(0x200, []byte{0x70, 0x01, 0x00, 0xFD, 0x12, 0x00})
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	chip8.SetClock(clk)
+
+	assert.ErrorIs(t, chip8.Run(), ErrExit)
+	assert.Equal(t, byte(1), chip8.V[0], "the loop stopped at 00FD")
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.Empty(t, clk.slept)
+}
+
+func TestInfiniteLoopWithoutHalt(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x12, 0x00} // JP 0x200
+	chip8.LoadBytes(0x200, testBytes)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, chip8.Step())
+		assert.Equal(t, uint16(0x200), chip8.PC)
+	}
+}
+
+func TestLongLoadIF000(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xF0, 0x00, 0x0A, 0xBC, 0x60, 0x69}
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x0ABC), chip8.I)
+	assert.Equal(t, uint16(0x204), chip8.PC)
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint8(0x69), chip8.V[0])
+}
+
+func TestSkipOverLongLoad(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x30, 0x00, 0xF0, 0x00, 0x12, 0x34, 0x60, 0x69}
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x206), chip8.PC)
+}
+
+func TestRunCycles(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x70, 0x01, 0x12, 0x00} // ADD V0, 1; JP 0x200
+	chip8.LoadBytes(0x200, testBytes)
+
+	steps := 0
+	chip8.SetTraceFunc(func(uint16, uint16, [16]byte) { steps++ })
+
+	assert.NoError(t, chip8.RunCycles(11))
+	assert.Equal(t, 11, steps)
+	assert.Equal(t, uint8(6), chip8.V[0])
+	assert.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func TestRunCyclesStopsOnError(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x70, 0x01, 0x00, 0x00}
+	chip8.LoadBytes(0x200, testBytes)
+
+	err := chip8.RunCycles(10)
+
+	assert.Error(t, err)
+	assert.Equal(t, uint8(1), chip8.V[0])
+	assert.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func BenchmarkRunCycles(b *testing.B) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x70, 0x01, 0x81, 0x04, 0x12, 0x00} // ADD V0, 1; ADD V1, V0; JP 0x200
+	chip8.LoadBytes(0x200, testBytes)
+
+	b.ResetTimer()
+	if err := chip8.RunCycles(b.N); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// BenchmarkRunROM measures opcode dispatch on a real game, with the timers
+// ticking every 16 instructions so it does not just spin on the delay timer.
+// Dispatching on op>>12 instead of op&0xF000 took it from ~25 to ~19 ns/op
+// (ExecuteOpcode ~135 to ~125 ns/op), the same jump table a [16]func table
+// would give but without splitting up ExecuteOpcode. Profile with
+//
+//	go test ./interpreter -run NONE -bench RunROM -cpuprofile cpu.out
+func BenchmarkRunROM(b *testing.B) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	if _, err := chip8.LoadRomFromFile("../roms/space_invaders.ch8"); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%16 == 0 {
+			chip8.updateTimers()
+		}
+		if err := chip8.Step(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDraw measures DRW of a 15 row sprite in high resolution, half
+// of the draws wrapping around the right edge. Packing the framebuffer into
+// bitsets left it at ~680 ns/op, the same as a byte per pixel, while taking
+// BenchmarkClear from ~5000 to ~32 ns/op and the framebuffer from 8KB to
+// 2KB, which also makes StepBack snapshots cheaper. Compare with
+//
+//	go test ./interpreter -run NONE -bench 'Draw|Clear' -tags bytedisplay
+func BenchmarkDraw(b *testing.B) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.Quirks.WrapSprites = true
+	chip8.setHires(true)
+	chip8.LoadBytes(0x300, bytes.Repeat([]byte{0xA5}, 15))
+	chip8.I = 0x300
+	chip8.V[1], chip8.V[2] = 60, 10
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chip8.V[0] = byte(i%2) * 124
+		if _, err := chip8.ExecuteOpcode(0xD01F); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkClear measures CLS in high resolution.
+func BenchmarkClear(b *testing.B) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.setHires(true)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := chip8.ExecuteOpcode(0x00E0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkExecuteOpcode measures ExecuteOpcode alone over one opcode from
+// every group.
+func BenchmarkExecuteOpcode(b *testing.B) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	ops := []uint16{
+		0x00E0, 0x1200, 0x3000, 0x4000, 0x5010, 0x6012, 0x7001, 0x8014,
+		0x9010, 0xA300, 0xB200, 0xC0FF, 0xD011, 0xE09E, 0xF007, 0xF01E,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chip8.PC = 0x200
+		chip8.I = 0x300
+		if _, err := chip8.ExecuteOpcode(ops[i%len(ops)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestNewChip8ReturnsPointer(t *testing.T) {
+	load := func(c *chip8) {
+		c.LoadRomBytes([]byte{0x60, 0x2A}) // LD V0, 0x2A
+		c.Step()
+	}
+	c := newTestChip8(DefaultMemorySize)
+
+	load(c)
+
+	assert.Equal(t, byte(0x2A), c.V[0])
+	assert.Equal(t, uint16(0x202), c.PC)
+	assert.NotSame(t, c, newTestChip8(DefaultMemorySize))
+}
+
+func TestSetRegisters(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	var v [16]byte
+	for i := range v {
+		v[i] = byte(0x10*i + i)
+	}
+
+	chip8.SetRegisters(v)
+
+	assert.Equal(t, v, chip8.Registers())
+	assert.Equal(t, byte(0x22), chip8.V[2])
+	regs := chip8.Registers()
+	regs[0] = 0xFF
+	assert.Equal(t, byte(0), chip8.V[0], "a copy")
+}
+
+func TestSnapshotWhileRunning(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetClockSpeed(6000)
+	// LD I, 0x300; loop: DRW V0, V0, 1; ADD V1, 1; LD V2, V1; JP loop
+	chip8.LoadBytes(0x200, []byte{0xA3, 0x00, 0xD0, 0x01, 0x71, 0x01, 0x82, 0x10, 0x12, 0x02})
+	chip8.LoadBytes(0x300, []byte{0x80})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			if i%2 == 0 {
+				chip8.Step()
+			} else {
+				chip8.RunFrame()
+			}
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		s := chip8.Snapshot()
+		// Between instructions V2 always catches up with V1 before DRW
+		if s.PC == 0x202 {
+			assert.Equal(t, s.V[1], s.V[2])
+		}
+		assert.Len(t, s.Frame, 32)
+	}
+	assert.Equal(t, chip8.Cycles(), chip8.Snapshot().Cycles)
+}
+
+func TestNewChip8WithMemory(t *testing.T) {
+	m, err := NewChip8WithMemory(0x10000)
+	assert.NoError(t, err)
+	chip8 := m.(*chip8)
+	assert.Len(t, chip8.memory, 0x10000)
+
+	rom := bytes.Repeat([]byte{0x00, 0xE0}, 0x800) // 4KB of CLS
+	n, err := chip8.LoadRomBytes(rom)
+	assert.NoError(t, err)
+	assert.Equal(t, len(rom), n)
+	assert.Equal(t, byte(0xE0), chip8.memory[0x11FF])
+
+	// LD I, LONG 0x1800; LD V1, [I]
+	chip8.LoadBytes(0x1400, []byte{0xF0, 0x00, 0x18, 0x00, 0xF1, 0x65})
+	chip8.LoadBytes(0x1800, []byte{0x42, 0x69})
+	chip8.PC = 0x1400
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, uint16(0x1802), chip8.I)
+	assert.Equal(t, uint8(0x42), chip8.V[0])
+	assert.Equal(t, uint8(0x69), chip8.V[1])
+}
+
+func TestNewChip8WithMemoryInvalidSize(t *testing.T) {
+	_, err := NewChip8WithMemory(0x200)
+	assert.Error(t, err)
+	_, err = NewChip8WithMemory(0x10001)
+	assert.Error(t, err)
+}
+
+func TestJumpPastSmallMemory(t *testing.T) {
+	for _, op := range []uint16{0x1FFE, 0x22FF, 0x2300} {
+		chip8 := newTestChip8(0x300)
+		chip8.LoadBytes(0x200, []byte{byte(op >> 8), byte(op)})
+
+		var err error
+		assert.NotPanics(t, func() { err = chip8.Step() })
+
+		var addrErr *AddressError
+		if assert.ErrorAs(t, err, &addrErr, "%04X", op) {
+			assert.Equal(t, int(op&0xFFF), addrErr.Addr)
+			assert.Equal(t, uint16(0x200), addrErr.PC)
+		}
+		assert.Equal(t, uint16(0x200), chip8.PC)
+		assert.Equal(t, uint8(0), chip8.SP, "nothing pushed")
+	}
+}
+
+func TestLongLoadOutOfRange(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0xF0, 0x00, 0x12, 0x34})
+
+	err := chip8.Step()
+
+	var addrErr *AddressError
+	if assert.True(t, errors.As(err, &addrErr)) {
+		assert.Equal(t, 0x1234, addrErr.Addr)
+		assert.Equal(t, uint16(0x200), addrErr.PC)
+	}
+}
+
+func TestFetchPastTheEnd(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.PC = 0xFFF
+
+	var err error
+	assert.NotPanics(t, func() { err = chip8.Step() })
+	var addrErr *AddressError
+	if assert.ErrorAs(t, err, &addrErr) {
+		assert.Equal(t, 0x1000, addrErr.Addr)
+		assert.Equal(t, uint16(0xFFF), addrErr.PC)
+	}
+	assert.Equal(t, uint16(0xFFF), chip8.PC)
+
+	// A skip on the last instruction steps off the end without peeking
+	chip8.LoadBytes(0xFFE, []byte{0x30, 0x00}) // SE V0, 0
+	chip8.PC = 0xFFE
+	assert.NotPanics(t, func() { err = chip8.Step() })
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x1002), chip8.PC)
+	assert.ErrorAs(t, chip8.Step(), &addrErr)
+}
+
+func TestRPLFlagsFx75Fx85(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD R, V3; LD V0, 0; LD V3, 0; LD V3, R
+	testBytes := []byte{0xF3, 0x75, 0x60, 0x00, 0x63, 0x00, 0xF3, 0x85}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V = [16]byte{0x11, 0x22, 0x33, 0x44, 0x55}
+
+	assert.NoError(t, chip8.RunCycles(3))
+	assert.Equal(t, [8]byte{0x11, 0x22, 0x33, 0x44}, chip8.rplFlags)
+	assert.Equal(t, uint8(0x00), chip8.V[0])
+	assert.Equal(t, uint8(0x00), chip8.V[3])
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, [16]byte{0x11, 0x22, 0x33, 0x44, 0x55}, chip8.V)
+}
+
+func TestRPLFlagsLimit(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xFF, 0x75, 0xFF, 0x85} // LD R, VF; LD VF, R
+	chip8.LoadBytes(0x200, testBytes)
+	for i := range chip8.V {
+		chip8.V[i] = byte(i + 1)
+	}
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, [8]byte{1, 2, 3, 4, 5, 6, 7, 8}, chip8.rplFlags)
+
+	chip8.V = [16]byte{}
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, [16]byte{1, 2, 3, 4, 5, 6, 7, 8}, chip8.V)
+}
+
+func TestStepInfoSkip(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x30, 0x00, 0x00, 0xE0, 0x30, 0x01} // SE V0, 0; CLS; SE V0, 1
+	chip8.LoadBytes(0x200, testBytes)
+
+	res, err := chip8.StepInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, StepResult{Opcode: 0x3000, PCBefore: 0x200, PCAfter: 0x204, Skipped: true}, res)
+
+	res, err = chip8.StepInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, StepResult{Opcode: 0x3001, PCBefore: 0x204, PCAfter: 0x206}, res)
+}
+
+func TestStepInfoDraw(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xD0, 0x01} // DRW V0, V0, 1
+	chip8.LoadBytes(0x200, testBytes)
+
+	res, err := chip8.StepInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, StepResult{Opcode: 0xD001, PCBefore: 0x200, PCAfter: 0x202, Drew: true}, res)
+}
+
+func TestStepInfoError(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xE0, 0x00}
+	chip8.LoadBytes(0x200, testBytes)
+
+	res, err := chip8.StepInfo()
+	assert.Error(t, err)
+	assert.Equal(t, StepResult{Opcode: 0xE000, PCBefore: 0x200, PCAfter: 0x200}, res)
+}
+
+func TestProtectReservedMemory(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.ProtectReservedMemory = true
+	chip8.LoadBytes(0x50, FontSet)              // Loading bypasses the protection
+	testBytes := []byte{0xA1, 0x80, 0xF1, 0x55} // LD I, 0x180; LD [I], V1
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[0], chip8.V[1] = 0x42, 0x69
+
+	assert.NoError(t, chip8.Step())
+	err := chip8.Step()
+
+	var perr *ProtectedWriteError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, uint16(0x180), perr.Addr)
+		assert.Equal(t, uint16(0x202), perr.PC)
+	}
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.Equal(t, []byte{0x00, 0x00}, chip8.memory[0x180:0x182])
+	assert.Equal(t, FontSet[0], chip8.memory[0x50])
+}
+
+func TestProtectReservedMemoryOff(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xA1, 0x80, 0xF1, 0x55} // LD I, 0x180; LD [I], V1
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[0], chip8.V[1] = 0x42, 0x69
+
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, []byte{0x42, 0x69}, chip8.memory[0x180:0x182])
+}
+
+func TestDelayTimerFx07(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xF3, 0x07} // LD V3, DT
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.SetDelayTimer(0x2A)
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint8(0x2A), chip8.V[3])
+
+	chip8.updateTimers()
+	assert.Equal(t, byte(0x29), chip8.DelayTimer())
+}
+
+func TestTimersFx15Fx18(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xF1, 0x15, 0xF2, 0x18} // LD DT, V1; LD ST, V2
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[1], chip8.V[2] = 3, 1
+
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, byte(3), chip8.DelayTimer())
+	assert.Equal(t, byte(1), chip8.SoundTimer())
+
+	chip8.updateTimers()
+	assert.Equal(t, byte(2), chip8.DelayTimer())
+	assert.Equal(t, byte(0), chip8.SoundTimer())
+}
+
+func TestTickTimers(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	spy := &spySound{}
+	chip8.SetSound(spy)
+	chip8.SetDelayTimer(5)
+	chip8.SetSoundTimer(2)
+
+	chip8.TickTimers(3)
+	assert.Equal(t, byte(2), chip8.DelayTimer())
+	assert.Equal(t, byte(0), chip8.SoundTimer())
+	assert.Equal(t, 1, spy.stops)
+
+	chip8.TickTimers(10)
+	assert.Equal(t, byte(0), chip8.DelayTimer())
+	assert.Equal(t, 1, spy.stops)
+}
+
+func TestLastTimerTick(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	chip8.SetClock(clk)
+	chip8.SetDelayTimer(3)
+	chip8.SetSoundTimer(2)
+	assert.True(t, chip8.LastTimerTick().IsZero())
+
+	assert.NoError(t, chip8.RunFrame())
+	first := chip8.LastTimerTick()
+	assert.Equal(t, [2]byte{2, 1}, [2]byte{chip8.DelayTimer(), chip8.SoundTimer()})
+
+	clk.Sleep(TimerPeriod)
+	assert.NoError(t, chip8.RunFrame())
+	assert.Equal(t, [2]byte{1, 0}, [2]byte{chip8.DelayTimer(), chip8.SoundTimer()})
+	assert.Equal(t, TimerPeriod, chip8.LastTimerTick().Sub(first))
+
+	chip8.Reset()
+	assert.True(t, chip8.LastTimerTick().IsZero())
+}
+
+func TestSkipUnknownOpcodes(t *testing.T) {
+	var buf bytes.Buffer
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetLogger(log.New(&buf, "", 0))
+	testBytes := []byte{0x60, 0x01, 0xE0, 0x00, 0x61, 0x02} // LD V0, 1; E000; LD V1, 2
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.Error(t, chip8.RunCycles(3))
+	assert.Equal(t, uint16(0x202), chip8.PC)
+
+	chip8.SkipUnknownOpcodes = true
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, uint16(0x206), chip8.PC)
+	assert.Equal(t, [2]byte{1, 2}, [2]byte{chip8.V[0], chip8.V[1]})
+	assert.Contains(t, buf.String(), "Skipping: Unknown opcode: 0xE000 at 0x0202")
+}
+
+func TestSYSStrict(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x01, 0x23} // SYS 0x123
+	chip8.LoadBytes(0x200, testBytes)
+
+	err := chip8.Step()
+
+	var uerr *UnknownOpcodeError
+	assert.ErrorAs(t, err, &uerr)
+	assert.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestSYSIgnored(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.IgnoreSysCalls = true
+	testBytes := []byte{0x01, 0x23, 0x00, 0xE0} // SYS 0x123; CLS
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, uint16(0x204), chip8.PC)
+}
+
+func TestStrictDecode(t *testing.T) {
+	for _, op := range []uint16{0x5121, 0x9121, 0x8129} {
+		chip8 := newTestChip8(DefaultMemorySize)
+		chip8.StrictDecode = true
+		chip8.LoadBytes(0x200, []byte{byte(op >> 8), byte(op)})
+
+		err := chip8.Step()
+
+		var uerr *UnknownOpcodeError
+		if assert.ErrorAs(t, err, &uerr, "%04X", op) {
+			assert.Equal(t, op, uerr.Opcode)
+		}
+		assert.Equal(t, uint16(0x200), chip8.PC)
+	}
+}
+
+func TestStrictDecodeOffByDefault(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// SE V1, V2 with a stray nibble; LD V0, 1; 8129
+	chip8.LoadBytes(0x200, []byte{0x51, 0x21, 0x60, 0x01, 0x81, 0x29})
+
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, uint16(0x206), chip8.PC)
+	assert.Equal(t, byte(0), chip8.V[0], "5121 treated as 5120 and skipped")
+}
+
+func TestClockSpeedPerInstance(t *testing.T) {
+	slow := newTestChip8(DefaultMemorySize)
+	fast := newTestChip8(DefaultMemorySize)
+	fast.SetClockSpeed(600)
+	for _, chip8 := range []*chip8{slow, fast} {
+		chip8.LoadBytes(0x200, []byte{0x70, 0x01, 0x12, 0x00}) // ADD V0, 1; JP 0x200
+	}
+
+	assert.Equal(t, DefaultClockSpeed, slow.ClockSpeed())
+	assert.Equal(t, 600, fast.ClockSpeed())
+
+	assert.NoError(t, slow.RunFrame())
+	assert.NoError(t, fast.RunFrame())
+	assert.Equal(t, uint8(1), slow.V[0])
+	assert.Equal(t, uint8(5), fast.V[0])
+}
+
+func TestSpeedMultiplier(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetClockSpeed(600)
+	chip8.LoadBytes(0x200, []byte{0x70, 0x01, 0x12, 0x00}) // ADD V0, 1; JP 0x200
+	assert.Equal(t, 1.0, chip8.SpeedMultiplier())
+
+	assert.NoError(t, chip8.RunFrame())
+	assert.Equal(t, uint64(10), chip8.cycles)
+
+	chip8.SetSpeedMultiplier(2)
+	assert.NoError(t, chip8.RunFrame())
+	assert.Equal(t, uint64(10+20), chip8.cycles)
+
+	chip8.SetSpeedMultiplier(0.5)
+	assert.NoError(t, chip8.RunFrame())
+	assert.Equal(t, uint64(10+20+5), chip8.cycles)
+	assert.Equal(t, 600, chip8.ClockSpeed(), "clock speed untouched")
+}
+
+func TestSpeedMultiplierClamped(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	chip8.SetSpeedMultiplier(1000)
+	assert.Equal(t, float64(MaxSpeedMultiplier), chip8.SpeedMultiplier())
+	chip8.SetSpeedMultiplier(0)
+	assert.Equal(t, MinSpeedMultiplier, chip8.SpeedMultiplier())
+
+	chip8.SetSpeedMultiplier(math.NaN())
+	assert.Equal(t, MinSpeedMultiplier, chip8.SpeedMultiplier(), "NaN ignored")
+
+	chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, chip8.RunFrame())
+	}
+	assert.Equal(t, uint64(1), chip8.cycles, "one instruction every ten frames")
+}
+
+func TestSpeedMultiplierFraction(t *testing.T) {
+	for _, tt := range []struct {
+		m      float64
+		cycles []uint64 // After each of four frames
+	}{
+		{0.5, []uint64{0, 1, 1, 2}},
+		{1.5, []uint64{1, 3, 4, 6}},
+	} {
+		chip8 := newTestChip8(DefaultMemorySize)   // DefaultClockSpeed, one instruction a frame
+		chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+		chip8.SetSpeedMultiplier(tt.m)
+
+		for _, want := range tt.cycles {
+			assert.NoError(t, chip8.RunFrame())
+			assert.Equal(t, want, chip8.cycles, "m=%g", tt.m)
+		}
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	spy := &spyDisplay{}
+	chip8.SetDisplay(spy)
+	chip8.LoadBytes(0x200, []byte{0x70, 0x01, 0x12, 0x00}) // ADD V0, 1; JP 0x200
+	chip8.SetDelayTimer(10)
+	chip8.SetPixel(0, 0, true)
+
+	chip8.Pause()
+	assert.True(t, chip8.Paused())
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, chip8.RunFrame())
+	}
+	assert.Equal(t, uint16(0x200), chip8.PC)
+	assert.Equal(t, uint8(0), chip8.V[0])
+	assert.Equal(t, byte(10), chip8.DelayTimer())
+	assert.Len(t, spy.frames, 1, "the display is still presented")
+
+	chip8.Resume()
+	assert.False(t, chip8.Paused())
+	assert.NoError(t, chip8.RunFrame())
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.Equal(t, uint8(1), chip8.V[0])
+	assert.Equal(t, byte(9), chip8.DelayTimer())
+}
+
+func TestPauseWhileRunning(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x70, 0x01, 0x12, 0x00}) // ADD V0, 1; JP 0x200
+	chip8.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*TimerPeriod)
+	defer cancel()
+	assert.ErrorIs(t, chip8.RunContext(ctx), context.DeadlineExceeded)
+	assert.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestStackOverflow(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x22, 0x00}) // CALL 0x200
+
+	assert.NoError(t, chip8.RunCycles(16))
+	assert.Equal(t, 16, chip8.StackDepth())
+
+	err := chip8.Step()
+	assert.ErrorIs(t, err, ErrStackOverflow)
+	var serr *StackError
+	if assert.ErrorAs(t, err, &serr) {
+		assert.Equal(t, uint16(0x200), serr.PC)
+	}
+	assert.EqualError(t, err, "stack overflow at 0x0200")
+	assert.Equal(t, 16, chip8.StackDepth())
+	assert.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestStackUnderflow(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x00, 0xE0, 0x00, 0xEE}) // CLS; RET
+
+	assert.NoError(t, chip8.Step())
+	err := chip8.Step()
+
+	assert.ErrorIs(t, err, ErrStackUnderflow)
+	assert.EqualError(t, err, "stack underflow at 0x0202")
+	assert.Equal(t, byte(0), chip8.SP)
+	assert.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func TestPushOverflow(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	for i := 0; i < 16; i++ {
+		assert.NoError(t, chip8.Push(0x200))
+	}
+	assert.Equal(t, ErrStackOverflow, chip8.Push(0x200))
+}
+
+func TestReset(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.Quirks = SuperChipQuirks
+	chip8.SetClockSpeed(600)
+	// HIGH; LD V3, 7; LD I, 0x300; LD B, V3; CALL 0x20C; -; LD ST, V3
+	_, err := chip8.LoadRomBytes([]byte{0x00, 0xFF, 0x63, 0x07, 0xA3, 0x00, 0xF3, 0x33, 0x22, 0x0C, 0x00, 0x00, 0xF3, 0x18})
+	assert.NoError(t, err)
+	assert.NoError(t, chip8.RunCycles(6))
+	chip8.I = FontBase
+	assert.NoError(t, chip8.Execute(Decode(0xD005))) // DRW V0, V0, 5
+	chip8.KeyDown(0x5)
+	chip8.Replay([]InputEvent{{Cycle: 1, Key: 0x9, Down: true}})
+
+	chip8.Reset()
+
+	assert.Equal(t, uint16(0x200), chip8.PC)
+	assert.Equal(t, uint16(0), chip8.I)
+	assert.Equal(t, [16]byte{}, chip8.V)
+	assert.Equal(t, 0, chip8.StackDepth())
+	assert.Equal(t, byte(0), chip8.SoundTimer())
+	assert.Equal(t, uint64(0), chip8.Cycles())
+	assert.Equal(t, [16]byte{}, chip8.keypad)
+	x, y, w, h := chip8.LastDrawDirtyRegion()
+	assert.Zero(t, x+y+w+h, "last draw forgotten")
+	assert.Empty(t, chip8.replay, "replay dropped")
+	w, h = chip8.Dimensions()
+	assert.Equal(t, 64, w)
+	assert.Equal(t, 32, h)
+	assert.Zero(t, chip8.memory[0x200], "ROM cleared")
+	assert.Zero(t, chip8.memory[0x302], "RAM cleared")
+	assert.Equal(t, FontSet, chip8.memory[FontBase:FontBase+len(FontSet)])
+	assert.Equal(t, LargeFontSet, chip8.memory[LargeFontBase:LargeFontBase+len(LargeFontSet)])
+
+	assert.Equal(t, SuperChipQuirks, chip8.Quirks, "configuration kept")
+	assert.Equal(t, 600, chip8.ClockSpeed())
+}
+
+func TestBCD(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0xF0, 0x33, 0xF0, 0x33}) // LD B, V0; LD B, V0
+	chip8.V[0] = 254
+	chip8.I = 0x300
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, []byte{2, 5, 4}, chip8.memory[0x300:0x303])
+
+	chip8.BCDWidth = 5
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, []byte{0, 0, 2, 5, 4}, chip8.memory[0x300:0x305])
+}
+
+func TestBCDPastTheEnd(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0xF0, 0x33}) // LD B, V0
+	chip8.V[0] = 123
+	chip8.I = 0xFFE
+	chip8.memory[0xFFE], chip8.memory[0xFFF] = 0xAA, 0xAA
+
+	err := chip8.Step()
+
+	var aerr *AddressError
+	if assert.ErrorAs(t, err, &aerr) {
+		assert.Equal(t, 0x1000, aerr.Addr)
+	}
+	assert.Equal(t, []byte{0xAA, 0xAA}, chip8.memory[0xFFE:], "nothing written")
+}
+
+func TestStorePastTheEnd(t *testing.T) {
+	for _, size := range []int{DefaultMemorySize, MaxMemorySize} {
+		chip8 := newTestChip8(size)
+		chip8.LoadBytes(0x200, []byte{0xF3, 0x55}) // LD [I], V3
+		chip8.V[0], chip8.V[1], chip8.V[2], chip8.V[3] = 1, 2, 3, 4
+		chip8.I = uint16(size - 2)
+
+		err := chip8.Step()
+
+		var aerr *AddressError
+		assert.ErrorAs(t, err, &aerr, "size %d", size)
+		assert.Equal(t, []byte{0, 0}, chip8.memory[size-2:], "nothing written")
+		assert.Equal(t, []byte{0, 0}, chip8.memory[:2], "no wrap to 0")
+	}
+}
+
+func TestMemoryAccessPastTheEnd(t *testing.T) {
+	for _, size := range []int{DefaultMemorySize, MaxMemorySize} {
+		for _, op := range []uint16{0xF033, 0xF155, 0xF165, 0xF002} {
+			chip8 := newTestChip8(size)
+			chip8.LoadBytes(0x200, []byte{byte(op >> 8), byte(op)})
+			chip8.I = uint16(size - 1)
+
+			err := chip8.Step()
+
+			var aerr *AddressError
+			if assert.ErrorAs(t, err, &aerr, "%04X", op) {
+				assert.Equal(t, size, aerr.Addr, "first address past the end of %d", size)
+			}
+			assert.Equal(t, uint16(0x200), chip8.PC)
+		}
+	}
+}
+
+// FuzzExecuteOpcode runs a single opcode from a random machine state, with
+// PC anywhere in or past memory, and checks neither it nor the instruction
+// after it panics and that it does not leave PC unchanged without an error,
+// besides the instructions that legitimately stay put. The seeds run as a normal
+// test; use go test -fuzz FuzzExecuteOpcode ./interpreter to explore more.
+func FuzzExecuteOpcode(f *testing.F) {
+	for _, op := range []uint16{
+		0x00E0, 0x00EE, 0x00FF, 0x00C5, 0x1200, 0x2400, 0x3112, 0x5120, 0x8126, 0x812E,
+		0xA123, 0xBFFF, 0xC1FF, 0xD12F, 0xD120, 0xE19E, 0xF10A, 0xF11E, 0xF129, 0xF130,
+		0xF133, 0xFF55, 0xFF65, 0xFF75, 0xFF85, 0xF000, 0xF002, 0xF301, 0x0000,
+	} {
+		f.Add(op, uint16(0x200), uint16(0x300), byte(0x12), byte(0x34), byte(0), false)
+		f.Add(op, uint16(0xFFC), uint16(0xFFF), byte(0xFF), byte(0xFF), byte(16), true)
+	}
+	for _, pc := range []uint16{0xFFE, 0xFFF, 0x1000, 0xFFFF} {
+		f.Add(uint16(0x3000), pc, uint16(0), byte(0), byte(0), byte(0), false)
+	}
+
+	chip8 := newTestChip8(DefaultMemorySize)
+	f.Fuzz(func(t *testing.T, op, pc, i uint16, vx, vy, sp byte, hires bool) {
+		chip8.Reset()
+		chip8.Quirks = Quirks{}
+		chip8.PC = pc
+		if int(pc) < len(chip8.memory) {
+			chip8.memory[pc] = byte(op >> 8)
+		}
+		if int(pc)+1 < len(chip8.memory) {
+			chip8.memory[pc+1] = byte(op)
+		}
+		chip8.I = i
+		chip8.V[op>>8&0xF], chip8.V[op>>4&0xF] = vx, vy
+		chip8.SP = sp % (uint8(len(chip8.stack)) + 1)
+		chip8.hires = hires
+
+		var err error
+		assert.NotPanics(t, func() { err = chip8.Step() }, "%04X at 0x%04X", op, pc)
+		if int(pc)+1 >= len(chip8.memory) {
+			assert.Error(t, err, "fetch at 0x%04X", pc)
+		}
+		// Wherever the instruction left PC, the next fetch must not panic
+		assert.NotPanics(t, func() { chip8.Step() }, "after %04X at 0x%04X", op, pc)
+
+		if err == nil && chip8.PC == pc {
+			// Jumps, calls and returns landing on themselves, or Fx0A
+			// waiting for a key
+			switch {
+			case op>>12 == 0x1, op>>12 == 0x2, op>>12 == 0xB, op == 0x00EE, op&0xF0FF == 0xF00A:
+			default:
+				t.Errorf("%04X at 0x%04X left PC unchanged", op, pc)
+			}
+		}
+	})
+}
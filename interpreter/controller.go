@@ -0,0 +1,164 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrForeignMachine is returned by NewController for a Machine that
+// NewChip8 did not build, such as a test stub.
+var ErrForeignMachine = errors.New("controller needs a machine built by NewChip8")
+
+// CommandKind says what a Command asks the Controller to do.
+type CommandKind uint8
+
+const (
+	CmdLoad            CommandKind = iota // Reset and load ROM, staying paused if paused
+	CmdPause                              // Pause emulation
+	CmdResume                             // Resume emulation, past a breakpoint too
+	CmdReset                              // Reset the machine, keeping nothing loaded
+	CmdKeyDown                            // Press Key
+	CmdKeyUp                              // Release Key
+	CmdBreakpoint                         // Pause before executing the instruction at Addr
+	CmdClearBreakpoint                    // Remove the breakpoint at Addr
+)
+
+// Command is sent to a Controller. Only the fields its Kind names are used.
+type Command struct {
+	Kind CommandKind
+	ROM  []byte
+	Key  uint8
+	Addr uint16
+}
+
+// Update is the machine state a Controller sends after every frame.
+type Update struct {
+	Frame  [][]byte // Copy of the framebuffer, as Frame returns
+	V      [16]byte
+	PC     uint16
+	Cycles uint64
+	Paused bool
+	Break  bool  // A breakpoint paused the machine in this frame
+	Err    error // The load or instruction that failed; the machine is paused
+}
+
+// Controller runs a machine on its own goroutine, driven entirely by
+// Commands and reporting back with Updates, so a UI event loop never
+// touches the machine or needs to lock it. The machine must not be used
+// directly once Start has been called.
+type Controller struct {
+	c        *chip8
+	commands chan Command
+	updates  chan Update
+	breaks   map[uint16]bool
+	resumed  bool  // Skip the breakpoint check once after a resume
+	err      error // A failed command, reported with the next Update
+}
+
+// NewController returns a controller for m, which must come from NewChip8,
+// NewChip8WithMemory or NewChip8Profile.
+func NewController(m Machine) (*Controller, error) {
+	c, ok := m.(*chip8)
+	if !ok {
+		return nil, ErrForeignMachine
+	}
+	return &Controller{
+		c:        c,
+		commands: make(chan Command),
+		updates:  make(chan Update),
+		breaks:   make(map[uint16]bool),
+	}, nil
+}
+
+// Commands is where the controller receives commands. They are applied
+// between frames.
+func (ctl *Controller) Commands() chan<- Command {
+	return ctl.commands
+}
+
+// Updates delivers one Update per 60Hz frame and must be drained; it is
+// closed when the controller stops.
+func (ctl *Controller) Updates() <-chan Update {
+	return ctl.updates
+}
+
+// Start runs the machine frame by frame, paced by its Clock, until ctx is
+// cancelled.
+func (ctl *Controller) Start(ctx context.Context) {
+	go ctl.run(ctx)
+}
+
+func (ctl *Controller) run(ctx context.Context) {
+	defer close(ctl.updates)
+	c := ctl.c
+	next := c.clock.Now().Add(TimerPeriod)
+	for {
+		for drained := false; !drained; {
+			select {
+			case cmd := <-ctl.commands:
+				ctl.apply(cmd)
+			case <-ctx.Done():
+				return
+			default:
+				drained = true
+			}
+		}
+
+		u := Update{Err: ctl.err}
+		ctl.err = nil
+		err := c.runFrame(func() bool {
+			if ctl.resumed {
+				ctl.resumed = false
+				return false
+			}
+			u.Break = ctl.breaks[c.PC]
+			return u.Break
+		})
+		if err != nil {
+			c.Pause()
+			u.Err = err
+		}
+		ctl.resumed = false
+		u.Frame, u.V, u.PC, u.Cycles, u.Paused = c.Frame(), c.V, c.PC, c.cycles, c.Paused()
+
+		// Keep taking commands while the UI is busy with the last update
+		for sent := false; !sent; {
+			select {
+			case ctl.updates <- u:
+				sent = true
+			case cmd := <-ctl.commands:
+				ctl.apply(cmd)
+			case <-ctx.Done():
+				return
+			}
+		}
+		next = c.waitFrame(next)
+	}
+}
+
+func (ctl *Controller) apply(cmd Command) {
+	c := ctl.c
+	switch cmd.Kind {
+	case CmdLoad:
+		c.Reset()
+		if _, err := c.LoadRomBytes(cmd.ROM); err != nil {
+			c.Pause()
+			ctl.err = err
+		}
+	case CmdPause:
+		c.Pause()
+	case CmdResume:
+		ctl.resumed = c.Paused()
+		c.Resume()
+	case CmdReset:
+		c.Reset()
+	case CmdKeyDown:
+		c.KeyDown(cmd.Key)
+	case CmdKeyUp:
+		c.KeyUp(cmd.Key)
+	case CmdBreakpoint:
+		ctl.breaks[cmd.Addr] = true
+	case CmdClearBreakpoint:
+		delete(ctl.breaks, cmd.Addr)
+	}
+}
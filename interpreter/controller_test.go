@@ -0,0 +1,107 @@
+package interpreter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nextUpdate waits for the first update matching ok.
+func nextUpdate(t *testing.T, ctl *Controller, ok func(Update) bool) Update {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case u := <-ctl.Updates():
+			if ok(u) {
+				return u
+			}
+		case <-timeout:
+			t.Fatal("no matching update")
+			return Update{}
+		}
+	}
+}
+
+func newTestController(t *testing.T) (*Controller, context.CancelFunc) {
+	c := newTestChip8(DefaultMemorySize)
+	c.SetClock(&fakeClock{now: time.Unix(0, 0)})
+	ctl, err := NewController(c)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	ctl.Start(ctx)
+	t.Cleanup(cancel)
+	return ctl, cancel
+}
+
+func TestController(t *testing.T) {
+	ctl, cancel := newTestController(t)
+	// LD I, 0x50 (font 0); DRW V0, V0, 5; ADD V1, 1; JP 0x204
+	rom := []byte{0xA0, 0x50, 0xD0, 0x05, 0x71, 0x01, 0x12, 0x04}
+
+	ctl.Commands() <- Command{Kind: CmdLoad, ROM: rom}
+	u := nextUpdate(t, ctl, func(u Update) bool { return u.V[1] >= 3 })
+	assert.False(t, u.Paused)
+	assert.Equal(t, byte(1), u.Frame[0][0], "glyph drawn")
+
+	ctl.Commands() <- Command{Kind: CmdPause}
+	u = nextUpdate(t, ctl, func(u Update) bool { return u.Paused })
+	paused := nextUpdate(t, ctl, func(Update) bool { return true })
+	assert.Equal(t, u.Cycles, paused.Cycles, "nothing runs while paused")
+	assert.Equal(t, u.V, paused.V)
+
+	ctl.Commands() <- Command{Kind: CmdResume}
+	nextUpdate(t, ctl, func(v Update) bool { return v.Cycles > u.Cycles })
+
+	cancel()
+	for range ctl.Updates() {
+	}
+}
+
+func TestControllerBreakpoint(t *testing.T) {
+	ctl, _ := newTestController(t)
+	// LD V0, 1; LD V1, 2; LD V2, 3; JP 0x206
+	rom := []byte{0x60, 0x01, 0x61, 0x02, 0x62, 0x03, 0x12, 0x06}
+
+	ctl.Commands() <- Command{Kind: CmdPause}
+	ctl.Commands() <- Command{Kind: CmdBreakpoint, Addr: 0x204}
+	ctl.Commands() <- Command{Kind: CmdLoad, ROM: rom}
+	ctl.Commands() <- Command{Kind: CmdResume}
+
+	u := nextUpdate(t, ctl, func(u Update) bool { return u.Break })
+	assert.True(t, u.Paused)
+	assert.Equal(t, uint16(0x204), u.PC)
+	assert.Equal(t, [3]byte{1, 2, 0}, [3]byte{u.V[0], u.V[1], u.V[2]})
+
+	// Resuming steps over the breakpoint it stopped at
+	ctl.Commands() <- Command{Kind: CmdResume}
+	u = nextUpdate(t, ctl, func(u Update) bool { return u.PC == 0x206 })
+	assert.False(t, u.Break)
+	assert.Equal(t, byte(3), u.V[2])
+}
+
+func TestControllerKeysAndErrors(t *testing.T) {
+	ctl, _ := newTestController(t)
+	// LD V0, K; SYS 0x001
+	rom := []byte{0xF0, 0x0A, 0x00, 0x01}
+
+	ctl.Commands() <- Command{Kind: CmdLoad, ROM: rom}
+	ctl.Commands() <- Command{Kind: CmdKeyDown, Key: 0xC}
+
+	u := nextUpdate(t, ctl, func(u Update) bool { return u.Err != nil })
+	var uerr *UnknownOpcodeError
+	assert.ErrorAs(t, u.Err, &uerr)
+	assert.True(t, u.Paused)
+	assert.Equal(t, byte(0xC), u.V[0])
+
+	ctl.Commands() <- Command{Kind: CmdLoad, ROM: make([]byte, 0x1000)}
+	u = nextUpdate(t, ctl, func(u Update) bool { return u.Err != nil })
+	assert.ErrorIs(t, u.Err, ErrRomTooLarge)
+}
+
+func TestNewControllerForeignMachine(t *testing.T) {
+	_, err := NewController(&stubMachine{})
+	assert.ErrorIs(t, err, ErrForeignMachine)
+}
@@ -0,0 +1,150 @@
+package interpreter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var opcodeReport = flag.String("opcodes", "", "write the opcode coverage summary to this file")
+
+// opcodeFamilies are the implemented instructions, every one of which the
+// suite must execute at least once on a machine from newTestChip8. The first family matching op&mask ==
+// value counts it.
+var opcodeFamilies = []struct {
+	name        string
+	mask, value uint16
+}{
+	{"00E0 CLS", 0xFFFF, 0x00E0},
+	{"00EE RET", 0xFFFF, 0x00EE},
+	{"00FD EXIT", 0xFFFF, 0x00FD},
+	{"00FE LOW", 0xFFFF, 0x00FE},
+	{"00FF HIGH", 0xFFFF, 0x00FF},
+	{"0nnn SYS", 0xF000, 0x0000},
+	{"1nnn JP", 0xF000, 0x1000},
+	{"2nnn CALL", 0xF000, 0x2000},
+	{"3xkk SE", 0xF000, 0x3000},
+	{"4xkk SNE", 0xF000, 0x4000},
+	{"5xy0 SE", 0xF00F, 0x5000},
+	{"6xkk LD", 0xF000, 0x6000},
+	{"7xkk ADD", 0xF000, 0x7000},
+	{"8xy0 LD", 0xF00F, 0x8000},
+	{"8xy1 OR", 0xF00F, 0x8001},
+	{"8xy2 AND", 0xF00F, 0x8002},
+	{"8xy3 XOR", 0xF00F, 0x8003},
+	{"8xy4 ADD", 0xF00F, 0x8004},
+	{"8xy5 SUB", 0xF00F, 0x8005},
+	{"8xy6 SHR", 0xF00F, 0x8006},
+	{"8xy7 SUBN", 0xF00F, 0x8007},
+	{"8xyE SHL", 0xF00F, 0x800E},
+	{"9xy0 SNE", 0xF00F, 0x9000},
+	{"Annn LD I", 0xF000, 0xA000},
+	{"Bnnn JP V0", 0xF000, 0xB000},
+	{"Cxkk RND", 0xF000, 0xC000},
+	{"Dxyn DRW", 0xF000, 0xD000},
+	{"Ex9E SKP", 0xF0FF, 0xE09E},
+	{"ExA1 SKNP", 0xF0FF, 0xE0A1},
+	{"F000 LD I, LONG", 0xFFFF, 0xF000},
+	{"Fn01 PLANE", 0xF0FF, 0xF001},
+	{"F002 AUDIO", 0xFFFF, 0xF002},
+	{"Fx07 LD Vx, DT", 0xF0FF, 0xF007},
+	{"Fx0A LD Vx, K", 0xF0FF, 0xF00A},
+	{"Fx15 LD DT", 0xF0FF, 0xF015},
+	{"Fx18 LD ST", 0xF0FF, 0xF018},
+	{"Fx1E ADD I", 0xF0FF, 0xF01E},
+	{"Fx29 LD F", 0xF0FF, 0xF029},
+	{"Fx30 LD HF", 0xF0FF, 0xF030},
+	{"Fx33 LD B", 0xF0FF, 0xF033},
+	{"Fx55 LD [I]", 0xF0FF, 0xF055},
+	{"Fx65 LD Vx, [I]", 0xF0FF, 0xF065},
+	{"Fx75 LD R", 0xF0FF, 0xF075},
+	{"Fx85 LD Vx, R", 0xF0FF, 0xF085},
+}
+
+// covered holds the machines newTestChip8 built while TestMain collects
+// coverage, as some tests create them on other goroutines.
+var covered struct {
+	sync.Mutex
+	collecting bool
+	machines   []*chip8
+}
+
+// newTestChip8 is newChip8 for the tests. While TestMain collects coverage
+// the machine counts its opcodes with EnableStats and is kept so its
+// OpcodeStats add to the summary.
+func newTestChip8(memorySize int) *chip8 {
+	c := newChip8(memorySize)
+	covered.Lock()
+	defer covered.Unlock()
+	if covered.collecting {
+		c.EnableStats = true
+		covered.machines = append(covered.machines, c)
+	}
+	return c
+}
+
+// TestMain runs the suite recording the opcodes it executes and, when the
+// whole suite ran, fails if an implemented opcode was never executed.
+// Benchmarks and fuzzing run without the stats so they do not skew them.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	whole := flagValue("test.run") == "" && flagValue("test.skip") == ""
+	measuring := flagValue("test.bench") != "" || flagValue("test.fuzz") != ""
+	if measuring {
+		os.Exit(m.Run())
+	}
+
+	covered.collecting = true
+	code := m.Run()
+	covered.collecting = false
+
+	summary, missing := opcodeSummary()
+	if *opcodeReport != "" {
+		if err := os.WriteFile(*opcodeReport, []byte(summary), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	}
+	if whole && len(missing) > 0 && code == 0 {
+		fmt.Fprintf(os.Stderr, "FAIL: opcodes never executed by the tests: %s\n", strings.Join(missing, ", "))
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// opcodeSummary adds up the OpcodeStats of the covered machines, formats
+// a line per family with its count and lists the families that never ran.
+func opcodeSummary() (string, []string) {
+	counts := make([]uint64, len(opcodeFamilies))
+	for _, c := range covered.machines {
+		for op, n := range c.OpcodeStats() {
+			for i, f := range opcodeFamilies {
+				if op&f.mask == f.value {
+					counts[i] += n
+					break
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	var missing []string
+	for i, f := range opcodeFamilies {
+		n := counts[i]
+		fmt.Fprintf(&b, "%-16s %d\n", f.name, n)
+		if n == 0 {
+			missing = append(missing, f.name)
+		}
+	}
+	return b.String(), missing
+}
+
+func flagValue(name string) string {
+	if f := flag.Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
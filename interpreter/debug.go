@@ -0,0 +1,191 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StepOverLimit caps how many instructions StepOver runs waiting for a
+// subroutine to return.
+const StepOverLimit = 1 << 20
+
+// StepOver executes one instruction, running a called subroutine to
+// completion: on a 2nnn CALL it keeps stepping until the matching RET brings
+// the stack back to its depth before the call. Any other instruction is a
+// plain Step.
+func (c *chip8) StepOver() error {
+	var op uint16 // Past the end of memory Step reports the AddressError
+	if int(c.PC)+1 < len(c.memory) {
+		op = uint16(c.memory[c.PC])<<8 | uint16(c.memory[c.PC+1])
+	}
+	depth := c.SP
+	err := c.Step()
+	if err != nil || op&0xF000 != 0x2000 {
+		return err
+	}
+	for i := 0; c.SP != depth; i++ {
+		if i == StepOverLimit {
+			return ErrStepOverLimit
+		}
+		err := c.Step()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CallStack returns a copy of the active return addresses, outermost call
+// first.
+func (c *chip8) CallStack() []uint16 {
+	stack := make([]uint16, c.SP)
+	copy(stack, c.stack[:c.SP])
+	return stack
+}
+
+// StackDepth is the number of subroutine calls currently active.
+func (c *chip8) StackDepth() int {
+	return int(c.SP)
+}
+
+// WatchFunc is called when an instruction changes a watched byte.
+type WatchFunc func(addr uint16, old, new byte)
+
+type watchpoint struct {
+	f WatchFunc
+}
+
+// WatchMemory calls f whenever an instruction changes the byte at addr.
+// Loading ROMs or bytes does not trigger watches. The returned function
+// removes the watch.
+func (c *chip8) WatchMemory(addr uint16, f WatchFunc) func() {
+	if c.watches == nil {
+		c.watches = make(map[uint16][]*watchpoint)
+	}
+	w := &watchpoint{f: f}
+	c.watches[addr] = append(c.watches[addr], w)
+	return func() {
+		ws := c.watches[addr]
+		for i := range ws {
+			if ws[i] == w {
+				c.watches[addr] = append(ws[:i:i], ws[i+1:]...)
+				break
+			}
+		}
+		if len(c.watches[addr]) == 0 {
+			delete(c.watches, addr)
+		}
+	}
+}
+
+func (c *chip8) notifyWatches(addr uint16, old, new byte) {
+	for _, w := range c.watches[addr] {
+		w.f(addr, old, new)
+	}
+}
+
+// CodeWriteFunc is called when the instruction at pc stores to addr inside
+// the loaded ROM.
+type CodeWriteFunc func(addr, pc uint16)
+
+// SetCodeWriteCallback calls f for every byte an instruction, Fx55 or Fx33,
+// stores between the entry point and the end of the ROM loaded by
+// LoadRomBytes: self-modifying programs patch code they run later this way,
+// but it may as well be data kept in the ROM. Stores outside a ROM, or
+// with no ROM loaded, are not reported. A nil f, the default, turns the
+// check off.
+func (c *chip8) SetCodeWriteCallback(f CodeWriteFunc) {
+	c.onCodeWrite = f
+}
+
+// DumpState formats the registers, I, PC, the stack and the timers, one
+// group per line.
+func (c *chip8) DumpState() string {
+	var b strings.Builder
+	for i, v := range c.V {
+		sep := " "
+		if i%8 == 7 {
+			sep = "\n"
+		}
+		fmt.Fprintf(&b, "V%X=%02X%s", i, v, sep)
+	}
+	fmt.Fprintf(&b, "I=%04X PC=%04X SP=%d\n", c.I, c.PC, c.SP)
+	b.WriteString("Stack:")
+	for _, addr := range c.CallStack() {
+		fmt.Fprintf(&b, " %04X", addr)
+	}
+	fmt.Fprintf(&b, "\nDT=%02X ST=%02X\n", c.delayTimer, c.soundTimer)
+	return b.String()
+}
+
+// PeekMemory returns a copy of n bytes of memory from addr, cut short at
+// the end of memory.
+func (c *chip8) PeekMemory(addr uint16, n int) []byte {
+	start := int(addr)
+	end := start + n
+	if end > len(c.memory) {
+		end = len(c.memory)
+	}
+	if start >= end {
+		return nil
+	}
+	return append([]byte(nil), c.memory[start:end]...)
+}
+
+// HexDump formats n bytes of memory from addr, 16 to a line, each line
+// prefixed with its address. The range is cut short at the end of memory.
+func (c *chip8) HexDump(addr uint16, n int) string {
+	start := int(addr)
+	end := start + n
+	if end > len(c.memory) {
+		end = len(c.memory)
+	}
+	var b strings.Builder
+	for line := start; line < end; line += 16 {
+		fmt.Fprintf(&b, "%04X:", line)
+		for i := line; i < line+16 && i < end; i++ {
+			fmt.Fprintf(&b, " %02X", c.memory[i])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// DisassembleLoaded disassembles the ROM loaded by LoadRomBytes (or
+// LoadRom), one line per two-byte word from the entry point to the end of
+// the ROM, e.g. "> 0202  6169  LD V1, 0x69". The line at PC is marked with
+// "> ", all others start with two spaces. Data mixed in with the code is shown as
+// whatever instruction it happens to decode to. Nothing is returned when no
+// ROM has been loaded.
+func (c *chip8) DisassembleLoaded() []string {
+	var lines []string
+	for addr := c.entry; addr < c.romEnd; addr += 2 {
+		op := uint16(c.memory[addr]) << 8
+		if addr+1 < len(c.memory) {
+			op |= uint16(c.memory[addr+1])
+		}
+		marker := "  "
+		if addr == int(c.PC) {
+			marker = "> "
+		}
+		lines = append(lines, fmt.Sprintf("%s%04X  %04X  %s", marker, addr, op, Disassemble(op)))
+	}
+	return lines
+}
+
+// OpcodeStats returns how many times each distinct opcode has executed
+// successfully while EnableStats was set, 00FD EXIT included.
+func (c *chip8) OpcodeStats() map[uint16]uint64 {
+	stats := make(map[uint16]uint64, len(c.stats))
+	for op, n := range c.stats {
+		stats[op] = n
+	}
+	return stats
+}
+
+func (c *chip8) countOpcode(op uint16) {
+	if c.stats == nil {
+		c.stats = make(map[uint16]uint64)
+	}
+	c.stats[op]++
+}
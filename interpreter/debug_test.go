@@ -0,0 +1,234 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepOverCall(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x23, 0x00, 0x60, 0x01}) // CALL 0x300; LD V0, 1
+	// ADD V1, 1; CALL 0x310; RET
+	chip8.LoadBytes(0x300, []byte{0x71, 0x01, 0x23, 0x10, 0x00, 0xEE})
+	chip8.LoadBytes(0x310, []byte{0x72, 0x01, 0x00, 0xEE}) // ADD V2, 1; RET
+
+	assert.NoError(t, chip8.StepOver())
+
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.Equal(t, uint8(0), chip8.SP)
+	assert.Equal(t, uint8(1), chip8.V[1])
+	assert.Equal(t, uint8(1), chip8.V[2])
+	assert.Equal(t, uint8(0), chip8.V[0])
+}
+
+func TestStepOverPlainInstruction(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x60, 0x01})
+
+	assert.NoError(t, chip8.StepOver())
+
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.Equal(t, uint8(1), chip8.V[0])
+}
+
+func TestStepOverPastTheEnd(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.PC = 0xFFF
+
+	var err error
+	assert.NotPanics(t, func() { err = chip8.StepOver() })
+
+	var aerr *AddressError
+	assert.ErrorAs(t, err, &aerr)
+	assert.Equal(t, uint16(0xFFF), chip8.PC)
+}
+
+func TestStepOverLimit(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x23, 0x00})
+	chip8.LoadBytes(0x300, []byte{0x13, 0x00}) // JP 0x300
+
+	assert.ErrorIs(t, chip8.StepOver(), ErrStepOverLimit)
+}
+
+func TestCallStack(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x23, 0x00}) // CALL 0x300
+	chip8.LoadBytes(0x300, []byte{0x24, 0x00}) // CALL 0x400
+	chip8.LoadBytes(0x400, []byte{0x00, 0xEE}) // RET
+
+	assert.Empty(t, chip8.CallStack())
+	assert.NoError(t, chip8.RunCycles(2))
+
+	assert.Equal(t, []uint16{0x202, 0x302}, chip8.CallStack())
+	assert.Equal(t, 2, chip8.StackDepth())
+
+	stack := chip8.CallStack()
+	stack[0] = 0x666
+	assert.Equal(t, uint16(0x202), chip8.stack[0])
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, []uint16{0x202}, chip8.CallStack())
+	assert.Equal(t, 1, chip8.StackDepth())
+}
+
+func TestWatchMemory(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0xF1, 0x55, 0xF1, 0x55}) // LD [I], V1; LD [I], V1
+	chip8.LoadBytes(0x301, []byte{0x11})
+	chip8.I = 0x300
+	chip8.V[1] = 0x69
+
+	type write struct {
+		addr     uint16
+		old, new byte
+	}
+	var writes []write
+	clear := chip8.WatchMemory(0x301, func(addr uint16, old, new byte) {
+		writes = append(writes, write{addr, old, new})
+	})
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, []write{{0x301, 0x11, 0x69}}, writes)
+
+	clear()
+	chip8.V[1] = 0x42
+	assert.NoError(t, chip8.Step())
+	assert.Len(t, writes, 1)
+	assert.Empty(t, chip8.watches)
+}
+
+func TestCodeWriteCallback(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	rom := make([]byte, 0x20)
+	// LD I, 0x210; LD [I], V1; LD I, 0x300; LD [I], V0
+	copy(rom, []byte{0xA2, 0x10, 0xF1, 0x55, 0xA3, 0x00, 0xF0, 0x55})
+	_, err := chip8.LoadRomBytes(rom)
+	assert.NoError(t, err)
+
+	type write struct{ addr, pc uint16 }
+	var writes []write
+	chip8.SetCodeWriteCallback(func(addr, pc uint16) {
+		writes = append(writes, write{addr, pc})
+	})
+
+	assert.NoError(t, chip8.RunCycles(4))
+	assert.Equal(t, []write{{0x210, 0x202}, {0x211, 0x202}}, writes, "0x300 is past the ROM")
+
+	chip8.SetCodeWriteCallback(nil)
+	chip8.PC = 0x202
+	chip8.I = 0x210
+	assert.NoError(t, chip8.Step())
+	assert.Len(t, writes, 2)
+}
+
+func TestWatchMemoryUnchangedByte(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0xF0, 0x55}) // LD [I], V0
+	chip8.I = 0x300
+
+	fired := false
+	chip8.WatchMemory(0x300, func(uint16, byte, byte) { fired = true })
+
+	assert.NoError(t, chip8.Step())
+	assert.False(t, fired)
+}
+
+func TestDumpState(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.V[0x0] = 0x12
+	chip8.V[0xA] = 0xBC
+	chip8.I = 0x0345
+	chip8.PC = 0x0208
+	chip8.Push(0x0202)
+	chip8.Push(0x0300)
+	chip8.SetDelayTimer(0x3C)
+
+	dump := chip8.DumpState()
+
+	assert.Equal(t, "V0=12 V1=00 V2=00 V3=00 V4=00 V5=00 V6=00 V7=00\n"+
+		"V8=00 V9=00 VA=BC VB=00 VC=00 VD=00 VE=00 VF=00\n"+
+		"I=0345 PC=0208 SP=2\n"+
+		"Stack: 0202 0300\n"+
+		"DT=3C ST=00\n", dump)
+}
+
+func TestPeekMemory(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x62, 0x69, 0x12, 0x00})
+
+	b := chip8.PeekMemory(0x200, 3)
+	assert.Equal(t, []byte{0x62, 0x69, 0x12}, b)
+	b[0] = 0
+	assert.Equal(t, byte(0x62), chip8.memory[0x200], "a copy")
+
+	assert.Len(t, chip8.PeekMemory(0xFFE, 16), 2)
+	assert.Empty(t, chip8.PeekMemory(0x1000, 16))
+}
+
+func TestDisassembleLoaded(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	_, err := chip8.LoadRomBytes([]byte{0x60, 0x01, 0x61, 0x69, 0x12, 0x02})
+	assert.NoError(t, err)
+	assert.NoError(t, chip8.RunCycles(1))
+
+	assert.Equal(t, []string{
+		"  0200  6001  LD V0, 0x01",
+		"> 0202  6169  LD V1, 0x69",
+		"  0204  1202  JP 0x202",
+	}, chip8.DisassembleLoaded())
+}
+
+func TestDisassembleLoadedNoRom(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x60, 0x01})
+
+	assert.Empty(t, chip8.DisassembleLoaded())
+}
+
+func TestHexDump(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x00, 0xE0, 0x12, 0x00})
+
+	assert.Equal(t, "0200: 00 E0 12\n", chip8.HexDump(0x200, 3))
+	assert.Equal(t, "0FF0: 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00\n", chip8.HexDump(0xFF0, 32))
+
+	dump := chip8.HexDump(0x1F8, 12)
+	assert.Equal(t, "01F8: 00 00 00 00 00 00 00 00 00 E0 12 00\n", dump)
+}
+
+func TestOpcodeStats(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.EnableStats = true
+	// LD V0, 0; loop: ADD V0, 1; SE V0, 10; JP loop; JP end
+	chip8.LoadBytes(0x200, []byte{0x60, 0x00, 0x70, 0x01, 0x30, 0x0A, 0x12, 0x02, 0x12, 0x08})
+
+	assert.NoError(t, chip8.RunCycles(1+10+10+9+1))
+
+	assert.Equal(t, map[uint16]uint64{
+		0x6000: 1,
+		0x7001: 10,
+		0x300A: 10,
+		0x1202: 9,
+		0x1208: 1,
+	}, chip8.OpcodeStats())
+}
+
+func TestOpcodeStatsExit(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.EnableStats = true
+	chip8.LoadBytes(0x200, []byte{0x00, 0xFD}) // EXIT
+
+	assert.ErrorIs(t, chip8.Step(), ErrExit)
+	assert.Equal(t, map[uint16]uint64{0x00FD: 1}, chip8.OpcodeStats())
+}
+
+func TestOpcodeStatsOffByDefault(t *testing.T) {
+	chip8 := newChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+
+	assert.NoError(t, chip8.RunCycles(5))
+	assert.Empty(t, chip8.OpcodeStats())
+	assert.Nil(t, chip8.stats)
+}
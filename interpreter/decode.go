@@ -0,0 +1,23 @@
+package interpreter
+
+// Instruction is an opcode split into the fields instructions use.
+type Instruction struct {
+	Op  uint16 // The whole opcode
+	X   uint8  // Second nibble, usually register Vx
+	Y   uint8  // Third nibble, usually register Vy
+	N   uint8  // Lowest nibble
+	KK  byte   // Lowest byte
+	NNN uint16 // Lowest 12 bits, usually an address
+}
+
+// Decode splits op into its instruction fields.
+func Decode(op uint16) Instruction {
+	return Instruction{
+		Op:  op,
+		X:   uint8((op & 0x0F00) >> 8),
+		Y:   uint8((op & 0x00F0) >> 4),
+		N:   uint8(op & 0x000F),
+		KK:  byte(op),
+		NNN: op & 0x0FFF,
+	}
+}
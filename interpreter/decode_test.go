@@ -0,0 +1,122 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		op   uint16
+		want Instruction
+	}{
+		{0x00E0, Instruction{Op: 0x00E0, X: 0x0, Y: 0xE, N: 0x0, KK: 0xE0, NNN: 0x0E0}},
+		{0x1234, Instruction{Op: 0x1234, X: 0x2, Y: 0x3, N: 0x4, KK: 0x34, NNN: 0x234}},
+		{0x3A69, Instruction{Op: 0x3A69, X: 0xA, Y: 0x6, N: 0x9, KK: 0x69, NNN: 0xA69}},
+		{0x5BC0, Instruction{Op: 0x5BC0, X: 0xB, Y: 0xC, N: 0x0, KK: 0xC0, NNN: 0xBC0}},
+		{0x8F3E, Instruction{Op: 0x8F3E, X: 0xF, Y: 0x3, N: 0xE, KK: 0x3E, NNN: 0xF3E}},
+		{0xA666, Instruction{Op: 0xA666, X: 0x6, Y: 0x6, N: 0x6, KK: 0x66, NNN: 0x666}},
+		{0xD125, Instruction{Op: 0xD125, X: 0x1, Y: 0x2, N: 0x5, KK: 0x25, NNN: 0x125}},
+		{0xE3A1, Instruction{Op: 0xE3A1, X: 0x3, Y: 0xA, N: 0x1, KK: 0xA1, NNN: 0x3A1}},
+		{0xF765, Instruction{Op: 0xF765, X: 0x7, Y: 0x6, N: 0x5, KK: 0x65, NNN: 0x765}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Decode(tt.op), "%04X", tt.op)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	tests := []struct {
+		op   uint16
+		want string
+	}{
+		{0x00E0, "CLS"},
+		{0x00EE, "RET"},
+		{0x0123, "SYS 0x123"},
+		{0x1204, "JP 0x204"},
+		{0x2300, "CALL 0x300"},
+		{0x3269, "SE V2, 0x69"},
+		{0x5240, "SE V2, V4"},
+		{0x5241, "DW 0x5241"},
+		{0x6269, "LD V2, 0x69"},
+		{0x8A34, "ADD VA, V3"},
+		{0x823E, "SHL V2, V3"},
+		{0x8239, "DW 0x8239"},
+		{0xA666, "LD I, 0x666"},
+		{0xB600, "JP V0, 0x600"},
+		{0xD015, "DRW V0, V1, 5"},
+		{0xE59E, "SKP V5"},
+		{0xF000, "LD I, LONG"},
+		{0xF201, "PLANE 2"},
+		{0xF355, "LD [I], V3"},
+		{0xF3FF, "DW 0xF3FF"},
+		{0xF302, "DW 0xF302"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Disassemble(tt.op), "%04X", tt.op)
+	}
+}
+
+func TestFetchDecodeExecute(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x83, 0x44}) // ADD V3, V4
+	chip8.V[3], chip8.V[4] = 0xF0, 0x20
+
+	op, err := chip8.Fetch()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x8344), op)
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.Equal(t, uint8(0xF0), chip8.V[3])
+
+	in := Decode(op)
+	assert.Equal(t, Instruction{Op: 0x8344, X: 3, Y: 4, N: 4, KK: 0x44, NNN: 0x344}, in)
+
+	assert.NoError(t, chip8.Execute(in))
+	assert.Equal(t, uint8(0x10), chip8.V[3])
+	assert.Equal(t, uint8(0x01), chip8.V[0xF])
+	assert.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func TestExecuteWithoutFetch(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.PC = 0x300
+
+	assert.NoError(t, chip8.Execute(Decode(0x1456))) // JP 0x456
+	assert.Equal(t, uint16(0x456), chip8.PC)
+
+	err := chip8.Execute(Decode(0xE0FF))
+	var uerr *UnknownOpcodeError
+	if assert.ErrorAs(t, err, &uerr) {
+		assert.Equal(t, uint16(0xE0FF), uerr.Opcode)
+	}
+}
+
+func TestExecuteHandBuiltInstruction(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.V[1], chip8.V[2] = 0x10, 0x20
+	chip8.PC = 0x202
+
+	// Only the family comes from Op; the operands are the decoded fields
+	assert.NoError(t, chip8.Execute(Instruction{Op: 0x8000, X: 1, Y: 2, N: 4})) // ADD V1, V2
+	assert.Equal(t, byte(0x30), chip8.V[1])
+
+	assert.NoError(t, chip8.Execute(Instruction{Op: 0xF000, X: 1, KK: 0x15})) // LD DT, V1
+	assert.Equal(t, byte(0x30), chip8.DelayTimer())
+
+	assert.NoError(t, chip8.Execute(Instruction{Op: 0x0000, NNN: 0x0E0})) // CLS
+}
+
+func TestFetchPastTheEndNoPanic(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0xFFC, []byte{0x30, 0x00}) // SE V0, 0
+	chip8.PC = 0xFFC
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x1000), chip8.PC, "skipped off the end")
+
+	var err error
+	assert.NotPanics(t, func() { _, err = chip8.Fetch() })
+	var aerr *AddressError
+	assert.ErrorAs(t, err, &aerr)
+	assert.Equal(t, uint16(0x1000), chip8.PC, "PC left alone")
+}
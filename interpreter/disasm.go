@@ -0,0 +1,106 @@
+package interpreter
+
+import "fmt"
+
+// Disassemble renders op in Cowgod's mnemonic syntax, e.g. "LD V2, 0x69".
+// Opcodes the interpreter does not know are rendered as a "DW" data word.
+func Disassemble(op uint16) string {
+	in := Decode(op)
+	switch op & 0xF000 {
+	case 0x0000:
+		switch op {
+		case 0x00E0:
+			return "CLS"
+		case 0x00EE:
+			return "RET"
+		case 0x00FD:
+			return "EXIT"
+		case 0x00FE:
+			return "LOW"
+		case 0x00FF:
+			return "HIGH"
+		}
+		return fmt.Sprintf("SYS 0x%03X", in.NNN)
+	case 0x1000:
+		return fmt.Sprintf("JP 0x%03X", in.NNN)
+	case 0x2000:
+		return fmt.Sprintf("CALL 0x%03X", in.NNN)
+	case 0x3000:
+		return fmt.Sprintf("SE V%X, 0x%02X", in.X, in.KK)
+	case 0x4000:
+		return fmt.Sprintf("SNE V%X, 0x%02X", in.X, in.KK)
+	case 0x5000:
+		if in.N == 0 {
+			return fmt.Sprintf("SE V%X, V%X", in.X, in.Y)
+		}
+	case 0x6000:
+		return fmt.Sprintf("LD V%X, 0x%02X", in.X, in.KK)
+	case 0x7000:
+		return fmt.Sprintf("ADD V%X, 0x%02X", in.X, in.KK)
+	case 0x8000:
+		names := map[uint8]string{
+			0x0: "LD", 0x1: "OR", 0x2: "AND", 0x3: "XOR", 0x4: "ADD",
+			0x5: "SUB", 0x6: "SHR", 0x7: "SUBN", 0xE: "SHL",
+		}
+		if name, ok := names[in.N]; ok {
+			return fmt.Sprintf("%s V%X, V%X", name, in.X, in.Y)
+		}
+	case 0x9000:
+		if in.N == 0 {
+			return fmt.Sprintf("SNE V%X, V%X", in.X, in.Y)
+		}
+	case 0xA000:
+		return fmt.Sprintf("LD I, 0x%03X", in.NNN)
+	case 0xB000:
+		return fmt.Sprintf("JP V0, 0x%03X", in.NNN)
+	case 0xC000:
+		return fmt.Sprintf("RND V%X, 0x%02X", in.X, in.KK)
+	case 0xD000:
+		return fmt.Sprintf("DRW V%X, V%X, %d", in.X, in.Y, in.N)
+	case 0xE000:
+		switch in.KK {
+		case 0x9E:
+			return fmt.Sprintf("SKP V%X", in.X)
+		case 0xA1:
+			return fmt.Sprintf("SKNP V%X", in.X)
+		}
+	case 0xF000:
+		switch in.KK {
+		case 0x00:
+			if in.X == 0 {
+				return "LD I, LONG"
+			}
+		case 0x01:
+			return fmt.Sprintf("PLANE %d", in.X&0x3)
+		case 0x02:
+			if in.X == 0 {
+				return "AUDIO"
+			}
+		case 0x07:
+			return fmt.Sprintf("LD V%X, DT", in.X)
+		case 0x0A:
+			return fmt.Sprintf("LD V%X, K", in.X)
+		case 0x15:
+			return fmt.Sprintf("LD DT, V%X", in.X)
+		case 0x18:
+			return fmt.Sprintf("LD ST, V%X", in.X)
+		case 0x1E:
+			return fmt.Sprintf("ADD I, V%X", in.X)
+		case 0x29:
+			return fmt.Sprintf("LD F, V%X", in.X)
+		case 0x33:
+			return fmt.Sprintf("LD B, V%X", in.X)
+		case 0x55:
+			return fmt.Sprintf("LD [I], V%X", in.X)
+		case 0x65:
+			return fmt.Sprintf("LD V%X, [I]", in.X)
+		case 0x30:
+			return fmt.Sprintf("LD HF, V%X", in.X)
+		case 0x75:
+			return fmt.Sprintf("LD R, V%X", in.X)
+		case 0x85:
+			return fmt.Sprintf("LD V%X, R", in.X)
+		}
+	}
+	return fmt.Sprintf("DW 0x%04X", op)
+}
@@ -0,0 +1,297 @@
+package interpreter
+
+import "fmt"
+
+// Display resolutions. The framebuffer is always allocated at high
+// resolution; in low resolution only its top-left corner is used.
+const (
+	LowResWidth   = 64
+	LowResHeight  = 32
+	HighResWidth  = 128
+	HighResHeight = 64
+)
+
+// Display is implemented by frontends that present the framebuffer.
+type Display interface {
+	// Draw receives the framebuffer as rows of XO-CHIP color indices: bit 0
+	// is set for pixels lit in plane 0 and bit 1 for plane 1, so plain
+	// CHIP-8 programs only ever produce 0 and 1.
+	Draw(frame [][]byte)
+}
+
+// ScaledDisplay is implemented by Display frontends that draw each CHIP-8
+// pixel as a block of output pixels or cells. SetScale is called with the
+// SetScale size when the display is installed and whenever it changes.
+type ScaledDisplay interface {
+	Display
+	SetScale(n int)
+}
+
+type nopDisplay struct{}
+
+func (nopDisplay) Draw([][]byte) {}
+
+// SetDisplay installs the frontend that presents the framebuffer. A nil
+// Display turns presenting off.
+func (c *chip8) SetDisplay(d Display) {
+	if d == nil {
+		d = nopDisplay{}
+	}
+	c.screen = d
+	if s, ok := d.(ScaledDisplay); ok {
+		s.SetScale(c.Scale())
+	}
+}
+
+// Scale returns how big SetScale asked each CHIP-8 pixel to be drawn.
+func (c *chip8) Scale() int {
+	if c.scale == 0 {
+		return 1
+	}
+	return c.scale
+}
+
+// SetScale sets how many output pixels wide and high frontends draw each
+// CHIP-8 pixel, passing it on to a ScaledDisplay and making ScreenshotPNG
+// scale by n instead of ScreenshotScale. A display may still adjust it for
+// its output, as the terminal doubles the width of every pixel to make up
+// for tall fonts. n below 1 restores the defaults.
+func (c *chip8) SetScale(n int) {
+	if n < 1 {
+		n = 0
+	}
+	c.scale = n
+	if s, ok := c.screen.(ScaledDisplay); ok {
+		s.SetScale(c.Scale())
+	}
+}
+
+// width is the logical display width for the current resolution.
+func (c *chip8) width() int {
+	if c.hires {
+		return HighResWidth
+	}
+	return LowResWidth
+}
+
+// height is the logical display height for the current resolution.
+func (c *chip8) height() int {
+	if c.hires {
+		return HighResHeight
+	}
+	return LowResHeight
+}
+
+// Dimensions returns the display size for the current resolution: 64x32,
+// or 128x64 after 00FF switches to SUPER-CHIP high resolution.
+func (c *chip8) Dimensions() (w, h int) {
+	return c.width(), c.height()
+}
+
+// setHires switches resolution, clearing every plane as SUPER-CHIP does.
+func (c *chip8) setHires(on bool) {
+	c.hires = on
+	c.display = framebuffer{}
+	c.dirty = true
+}
+
+// Frame returns a copy of the framebuffer at the current resolution as rows
+// of color indices.
+func (c *chip8) Frame() [][]byte {
+	frame := make([][]byte, c.height())
+	for y := range frame {
+		frame[y] = make([]byte, c.width())
+		for x := range frame[y] {
+			frame[y][x] = c.display.at(x, y)
+		}
+	}
+	return frame
+}
+
+// Framebuffer returns a copy of the framebuffer at the current resolution
+// with a pixel set if it is lit in any plane.
+func (c *chip8) Framebuffer() [][]bool {
+	frame := make([][]bool, c.height())
+	for y := range frame {
+		frame[y] = make([]bool, c.width())
+		for x := range frame[y] {
+			frame[y][x] = c.display.lit(x, y)
+		}
+	}
+	return frame
+}
+
+// SetFrameCallback installs f to be called at the end of every frame in
+// which the display changed. A nil f removes it.
+func (c *chip8) SetFrameCallback(f func(frame [][]bool)) {
+	c.onFrame = f
+}
+
+// Pixel reports whether the pixel at (x, y) is lit in any plane. Coordinates
+// outside the current resolution are never lit.
+func (c *chip8) Pixel(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.width() || y >= c.height() {
+		return false
+	}
+	return c.display.lit(x, y)
+}
+
+// SetPixel lights or clears the pixel at (x, y) in the selected planes.
+// Coordinates outside the current resolution are ignored.
+func (c *chip8) SetPixel(x, y int, on bool) {
+	if x < 0 || y < 0 || x >= c.width() || y >= c.height() {
+		return
+	}
+	c.display.set(x, y, c.planes, on)
+	c.dirty = true
+}
+
+// Collision reports whether the last DRW erased any lit pixel, i.e. the VF
+// value it produced.
+func (c *chip8) Collision() bool {
+	return c.collision
+}
+
+// drawRegion is the bounding box of the pixels a DRW flipped: x0,y0 up to
+// but not including x1,y1.
+type drawRegion struct {
+	x0, y0, x1, y1 uint16
+}
+
+// add grows r to cover the pixel at x, y.
+func (r *drawRegion) add(x, y uint16) {
+	if r.x1 == 0 {
+		*r = drawRegion{x, y, x + 1, y + 1}
+		return
+	}
+	if x < r.x0 {
+		r.x0 = x
+	}
+	if y < r.y0 {
+		r.y0 = y
+	}
+	if x >= r.x1 {
+		r.x1 = x + 1
+	}
+	if y >= r.y1 {
+		r.y1 = y + 1
+	}
+}
+
+// LastDrawDirtyRegion returns the smallest rectangle holding every pixel the
+// last DRW flipped, for frontends that only redraw what changed. It is
+// empty, w and h 0, if the sprite flipped nothing. A sprite wrapping around
+// an edge gives a rectangle spanning the screen between its parts.
+func (c *chip8) LastDrawDirtyRegion() (x, y, w, h int) {
+	r := c.lastDraw
+	return int(r.x0), int(r.y0), int(r.x1 - r.x0), int(r.y1 - r.y0)
+}
+
+// SetPersistence makes pixels that turn off fade out over frames frames,
+// like the phosphor of a CRT, instead of going dark at once, so sprites that
+// are erased and redrawn every frame stop flickering. Read the result with
+// Intensity. The framebuffer itself stays on or off; 0, the default, turns
+// fading off.
+func (c *chip8) SetPersistence(frames int) {
+	c.persistence = frames
+	c.fade = nil
+	if frames > 0 {
+		c.fade = new([HighResHeight][HighResWidth]float32)
+		c.updateFade()
+	}
+}
+
+// Intensity returns the brightness of every pixel at the current resolution
+// as of the last frame: 1 for lit pixels, falling to 0 over the
+// SetPersistence frames after they turn off. Without persistence it is just
+// 1 or 0 for each pixel now.
+func (c *chip8) Intensity() [][]float32 {
+	frame := make([][]float32, c.height())
+	for y := range frame {
+		frame[y] = make([]float32, c.width())
+		for x := range frame[y] {
+			if c.fade != nil {
+				frame[y][x] = c.fade[y][x]
+			} else if c.display.lit(x, y) {
+				frame[y][x] = 1
+			}
+		}
+	}
+	return frame
+}
+
+// updateFade lights the pixels that are on and dims the rest by a frame.
+func (c *chip8) updateFade() {
+	step := 1 / float32(c.persistence)
+	for y := range c.fade {
+		for x := range c.fade[y] {
+			switch v := c.fade[y][x]; {
+			case c.display.lit(x, y):
+				c.fade[y][x] = 1
+			case v > step:
+				c.fade[y][x] = v - step
+			default:
+				c.fade[y][x] = 0
+			}
+		}
+	}
+}
+
+// present hands the framebuffer to the display and frame callback if it
+// changed since the last call.
+func (c *chip8) present() {
+	if !c.dirty {
+		return
+	}
+	c.dirty = false
+	c.screen.Draw(c.Frame())
+	if c.onFrame != nil {
+		c.onFrame(c.Framebuffer())
+	}
+}
+
+// DisplayMapSize is how many bytes of memory MapDisplay covers: the high
+// resolution framebuffer at one bit per pixel.
+const DisplayMapSize = HighResWidth * HighResHeight / 8
+
+// MapDisplay mirrors the framebuffer into the DisplayMapSize bytes of memory
+// from addr, for experimental programs that read the screen back. This is
+// not part of any CHIP-8 variant. Each row of the current resolution takes
+// width/8 bytes, the leftmost pixel in the high bit, and a pixel is set when
+// it is lit in any plane; in low resolution the bytes past the last row
+// read 0. Fx65, DRW and F002 read through the mirror, while stores land in
+// the memory underneath, which reappears after UnmapDisplay. PeekMemory and
+// HexDump always show that underlying memory.
+func (c *chip8) MapDisplay(addr uint16) error {
+	if addr < programStart || int(addr)+DisplayMapSize > len(c.memory) {
+		return fmt.Errorf("display map at 0x%04X outside 0x%X-0x%X", addr, programStart, len(c.memory)-DisplayMapSize)
+	}
+	c.displayMap = int(addr)
+	return nil
+}
+
+// UnmapDisplay turns the MapDisplay mirror off.
+func (c *chip8) UnmapDisplay() {
+	c.displayMap = 0
+}
+
+// readMemory is what instructions read at addr, which must be inside
+// memory: the MapDisplay mirror if it covers addr, memory otherwise.
+func (c *chip8) readMemory(addr int) byte {
+	if c.displayMap == 0 || addr < c.displayMap || addr >= c.displayMap+DisplayMapSize {
+		return c.memory[addr]
+	}
+	i := addr - c.displayMap
+	rowBytes := c.width() / 8
+	y, x := i/rowBytes, i%rowBytes*8
+	if y >= c.height() {
+		return 0
+	}
+	var b byte
+	for j := 0; j < 8; j++ {
+		if c.display.lit(x+j, y) {
+			b |= 0x80 >> j
+		}
+	}
+	return b
+}
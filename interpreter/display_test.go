@@ -0,0 +1,407 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type spyDisplay struct {
+	frames [][][]byte
+}
+
+func (d *spyDisplay) Draw(frame [][]byte) { d.frames = append(d.frames, frame) }
+
+func TestDrawSelectedPlane(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// PLANE 2; LD I, 0x300; DRW V0, V0, 1
+	testBytes := []byte{0xF2, 0x01, 0xA3, 0x00, 0xD0, 0x01}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0xC0})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, chip8.Step())
+	}
+
+	frame := chip8.Frame()
+	assert.Equal(t, byte(0x2), frame[0][0])
+	assert.Equal(t, byte(0x2), frame[0][1])
+	assert.Equal(t, byte(0x0), frame[0][2])
+	assert.Equal(t, byte(0x0), frame[0][0]&0x1)
+}
+
+func TestDrawBothPlanes(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// PLANE 3; LD I, 0x300; DRW V0, V0, 1
+	testBytes := []byte{0xF3, 0x01, 0xA3, 0x00, 0xD0, 0x01}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0x80, 0x40})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, chip8.Step())
+	}
+
+	frame := chip8.Frame()
+	assert.Equal(t, byte(0x1), frame[0][0])
+	assert.Equal(t, byte(0x2), frame[0][1])
+}
+
+func TestCLSSelectedPlane(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// PLANE 1; CLS
+	testBytes := []byte{0xF1, 0x01, 0x00, 0xE0}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.display.set(0, 0, 0x3, true)
+
+	assert.NoError(t, chip8.Step())
+	assert.NoError(t, chip8.Step())
+
+	assert.Equal(t, byte(0x2), chip8.display.at(0, 0))
+}
+
+func TestPresentOnlyWhenDirty(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	spy := &spyDisplay{}
+	chip8.SetDisplay(spy)
+
+	chip8.present()
+	assert.Len(t, spy.frames, 0)
+
+	chip8.display.set(2, 1, 0x1, true)
+	chip8.dirty = true
+	chip8.present()
+	chip8.present()
+	if assert.Len(t, spy.frames, 1) {
+		assert.Equal(t, byte(0x1), spy.frames[0][1][2])
+	}
+}
+
+func TestPixelAndCollision(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD I, 0x300; DRW V0, V0, 1
+	testBytes := []byte{0xA3, 0x00, 0xD0, 0x01}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0xC0})
+
+	chip8.SetPixel(1, 0, true)
+	assert.True(t, chip8.Pixel(1, 0))
+	assert.False(t, chip8.Pixel(0, 0))
+
+	assert.NoError(t, chip8.Step())
+	assert.NoError(t, chip8.Step())
+
+	assert.True(t, chip8.Collision())
+	assert.True(t, chip8.Pixel(0, 0))
+	assert.False(t, chip8.Pixel(1, 0))
+
+	chip8.SetPixel(0, 0, false)
+	assert.False(t, chip8.Pixel(0, 0))
+}
+
+func TestDRWCollision(t *testing.T) {
+	tests := []struct {
+		name      string
+		x, y      byte
+		collision byte
+	}{
+		{"overlapping", 4, 1, 1},
+		{"apart", 20, 10, 0},
+		{"overlap after wrap", 64 + 4, 32 + 1, 1},
+		{"wrapped over the edge", 60, 30, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chip8 := newTestChip8(DefaultMemorySize)
+			// LD I, 0x300; DRW V0, V1, 2; DRW V2, V3, 2
+			testBytes := []byte{0xA3, 0x00, 0xD0, 0x12, 0xD2, 0x32}
+			chip8.LoadBytes(0x200, testBytes)
+			chip8.LoadBytes(0x300, []byte{0xFF, 0xFF})
+			chip8.V[0], chip8.V[1] = 0, 0
+			chip8.V[2], chip8.V[3] = tt.x, tt.y
+
+			assert.NoError(t, chip8.RunCycles(3))
+			assert.Equal(t, tt.collision, chip8.V[0xF])
+			assert.Equal(t, tt.collision == 1, chip8.Collision())
+		})
+	}
+}
+
+func TestDRWCollisionWrappedPixels(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD I, 0x300; DRW V0, V0, 1; LD I, 0x301; DRW V1, V0, 1
+	testBytes := []byte{0xA3, 0x00, 0xD0, 0x01, 0xA3, 0x01, 0xD1, 0x01}
+	chip8.LoadBytes(0x200, testBytes)
+	// Pixel 6 of the first sprite, and pixel 7 of the second drawn at x=63,
+	// which wraps around onto x=6
+	chip8.LoadBytes(0x300, []byte{0x02, 0x01})
+	chip8.Quirks.WrapSprites = true
+	chip8.V[1] = 63
+
+	assert.NoError(t, chip8.RunCycles(4))
+	assert.Equal(t, byte(1), chip8.V[0xF])
+	assert.False(t, chip8.Pixel(6, 0))
+}
+
+func TestDRWCoordinateInVF(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD I, 0x300; DRW VF, VF, 1
+	testBytes := []byte{0xA3, 0x00, 0xDF, 0xF1}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0x80})
+	chip8.V[0xF] = 5
+
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.True(t, chip8.Pixel(5, 5))
+	assert.Equal(t, byte(0), chip8.V[0xF])
+}
+
+func TestDRWWithInjectedSprite(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	_, err := chip8.LoadRomBytes([]byte{0xD0, 0x13, 0xD0, 0x13}) // DRW V0, V1, 3 twice
+	assert.NoError(t, err)
+	_, err = chip8.LoadAt(0x600, []byte{0xFF, 0x81, 0xFF})
+	assert.NoError(t, err)
+	chip8.SetIndexRegister(0x600)
+	chip8.SetRegisters([16]byte{2, 1})
+
+	assert.NoError(t, chip8.Step())
+
+	frame := chip8.Framebuffer()
+	for x := 0; x < 8; x++ {
+		assert.True(t, frame[1][2+x], "top row")
+		assert.True(t, frame[3][2+x], "bottom row")
+		assert.Equal(t, x == 0 || x == 7, frame[2][2+x], "middle row")
+	}
+	assert.False(t, chip8.Collision())
+	assert.Equal(t, uint16(0x600), chip8.IndexRegister())
+
+	assert.NoError(t, chip8.Step())
+	assert.True(t, chip8.Collision())
+	assert.Equal(t, byte(1), chip8.Registers()[0xF])
+	assert.False(t, chip8.Pixel(2, 1))
+}
+
+func TestLastDrawDirtyRegion(t *testing.T) {
+	tests := []struct {
+		name       string
+		x, y       byte
+		wrap       bool
+		rx, ry     int
+		rw, rh     int
+		sprite     []byte
+		spriteRows byte
+	}{
+		{"sprite", 10, 4, false, 11, 4, 5, 3, []byte{0x7C, 0x00, 0x44}, 3},
+		{"clipped at the edge", 60, 30, false, 61, 30, 3, 1, []byte{0x7C, 0x00, 0x44}, 3},
+		{"wrapped", 62, 0, true, 0, 0, 64, 1, []byte{0xF0}, 1},
+		{"empty sprite", 10, 4, false, 0, 0, 0, 0, []byte{0x00}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chip8 := newTestChip8(DefaultMemorySize)
+			// LD I, 0x300; DRW V0, V1, n
+			chip8.LoadBytes(0x200, []byte{0xA3, 0x00, 0xD0, 0x10 | tt.spriteRows})
+			chip8.LoadBytes(0x300, tt.sprite)
+			chip8.Quirks.WrapSprites = tt.wrap
+			chip8.V[0], chip8.V[1] = tt.x, tt.y
+
+			assert.NoError(t, chip8.RunCycles(2))
+			x, y, w, h := chip8.LastDrawDirtyRegion()
+			assert.Equal(t, [4]int{tt.rx, tt.ry, tt.rw, tt.rh}, [4]int{x, y, w, h})
+		})
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetPersistence(4)
+	// LD I, 0x300; DRW V0, V0, 1; DRW V0, V0, 1; JP 0x206
+	chip8.LoadBytes(0x200, []byte{0xA3, 0x00, 0xD0, 0x01, 0xD0, 0x01, 0x12, 0x06})
+	chip8.LoadBytes(0x300, []byte{0x80})
+
+	assert.NoError(t, chip8.RunFrame()) // LD I
+	assert.NoError(t, chip8.RunFrame()) // Drawn
+	assert.Equal(t, float32(1), chip8.Intensity()[0][0])
+	assert.NoError(t, chip8.RunFrame()) // Erased
+	assert.False(t, chip8.Pixel(0, 0))
+
+	var fade []float32
+	for i := 0; i < 4; i++ {
+		fade = append(fade, chip8.Intensity()[0][0])
+		assert.NoError(t, chip8.RunFrame())
+	}
+	assert.InDeltaSlice(t, []float32{0.75, 0.5, 0.25, 0}, fade, 1e-6)
+	assert.Zero(t, chip8.Intensity()[0][1])
+}
+
+func TestIntensityWithoutPersistence(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetPixel(3, 2, true)
+
+	intensity := chip8.Intensity()
+
+	assert.Len(t, intensity, 32)
+	assert.Len(t, intensity[0], 64)
+	assert.Equal(t, float32(1), intensity[2][3])
+	assert.Zero(t, intensity[2][4])
+}
+
+func TestPixelOutOfBounds(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	chip8.SetPixel(64, 0, true)
+	chip8.SetPixel(0, 32, true)
+	chip8.SetPixel(-1, -1, true)
+
+	assert.False(t, chip8.Pixel(64, 0))
+	assert.False(t, chip8.Pixel(0, 32))
+	assert.False(t, chip8.Pixel(-1, -1))
+}
+
+func TestLowResBounds(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD I, 0x300; DRW V0, V1, 1
+	testBytes := []byte{0xA3, 0x00, 0xD0, 0x11}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0xC0})
+	chip8.Quirks.WrapSprites = true
+	chip8.V[0] = 63
+	chip8.V[1] = 31
+
+	assert.NoError(t, chip8.Step())
+	assert.NoError(t, chip8.Step())
+
+	frame := chip8.Frame()
+	assert.Len(t, frame, LowResHeight)
+	assert.Len(t, frame[0], LowResWidth)
+	assert.True(t, chip8.Pixel(63, 31))
+	assert.True(t, chip8.Pixel(0, 31))
+	assert.False(t, chip8.Pixel(64, 31))
+}
+
+func TestHighResBounds(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// HIGH; LD I, 0x300; DRW V0, V1, 1
+	testBytes := []byte{0x00, 0xFF, 0xA3, 0x00, 0xD0, 0x11}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0xC0})
+	chip8.V[0] = 63
+	chip8.V[1] = 31
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, chip8.Step())
+	}
+
+	frame := chip8.Frame()
+	assert.Len(t, frame, HighResHeight)
+	assert.Len(t, frame[0], HighResWidth)
+	assert.True(t, chip8.Pixel(63, 31))
+	assert.True(t, chip8.Pixel(64, 31))
+	assert.False(t, chip8.Pixel(0, 31))
+}
+
+func TestSwitchResolutionClears(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x00, 0xFF, 0x00, 0xFE} // HIGH; LOW
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.SetPixel(3, 3, true)
+
+	assert.NoError(t, chip8.Step())
+	assert.False(t, chip8.Pixel(3, 3))
+	chip8.SetPixel(100, 40, true)
+	assert.True(t, chip8.Pixel(100, 40))
+
+	assert.NoError(t, chip8.Step())
+	assert.Len(t, chip8.Frame(), LowResHeight)
+	assert.False(t, chip8.Pixel(100, 40))
+}
+
+func TestFrameCallback(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD I, 0x300; DRW V0, V0, 1; JP 0x204
+	testBytes := []byte{0xA3, 0x00, 0xD0, 0x01, 0x12, 0x04}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0x80})
+
+	var frames [][][]bool
+	chip8.SetFrameCallback(func(frame [][]bool) {
+		frames = append(frames, frame)
+	})
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, chip8.RunFrame())
+	}
+
+	if assert.Len(t, frames, 1) {
+		assert.Len(t, frames[0], LowResHeight)
+		assert.True(t, frames[0][0][0])
+		assert.False(t, frames[0][0][1])
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x00, 0xFF, 0x00, 0xFE} // HIGH; LOW
+	chip8.LoadBytes(0x200, testBytes)
+
+	w, h := chip8.Dimensions()
+	assert.Equal(t, []int{LowResWidth, LowResHeight}, []int{w, h})
+
+	assert.NoError(t, chip8.Step())
+	w, h = chip8.Dimensions()
+	assert.Equal(t, []int{HighResWidth, HighResHeight}, []int{w, h})
+
+	assert.NoError(t, chip8.Step())
+	w, h = chip8.Dimensions()
+	assert.Equal(t, []int{LowResWidth, LowResHeight}, []int{w, h})
+}
+
+func TestMapDisplay(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	assert.NoError(t, chip8.MapDisplay(0xC00))
+	// LD I, sprite; LD V1, 8; DRW V1, V0, 2; LD I, 0xC00; LD V2, [I]; sprite
+	testBytes := []byte{
+		0xA2, 0x0C, 0x61, 0x08, 0xD1, 0x02, 0xAC, 0x00, 0xF2, 0x65, 0x12, 0x0A,
+		0xF0, 0x81,
+	}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.memory[0xC01] = 0xAA // Hidden by the mirror
+
+	assert.NoError(t, chip8.RunCycles(5))
+	assert.Equal(t, [3]byte{0x00, 0xF0, 0x00}, [3]byte{chip8.V[0], chip8.V[1], chip8.V[2]})
+	assert.Equal(t, byte(0x81), chip8.readMemory(0xC00+8+1), "row 1 starts 8 bytes on")
+	assert.Equal(t, []byte{0x00, 0xAA}, chip8.PeekMemory(0xC00, 2), "memory itself is untouched")
+
+	chip8.UnmapDisplay()
+	assert.Equal(t, byte(0xAA), chip8.readMemory(0xC01))
+}
+
+func TestMapDisplayOutOfRange(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	assert.Error(t, chip8.MapDisplay(0x100))
+	assert.Error(t, chip8.MapDisplay(0xE01))
+	assert.NoError(t, chip8.MapDisplay(0xC00))
+}
+
+// scaledSpy is a ScaledDisplay recording the scales it was given.
+type scaledSpy struct {
+	spyDisplay
+	scales []int
+}
+
+func (s *scaledSpy) SetScale(n int) { s.scales = append(s.scales, n) }
+
+func TestSetScale(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	assert.Equal(t, 1, chip8.Scale())
+	chip8.SetScale(3)
+	spy := &scaledSpy{}
+
+	chip8.SetDisplay(spy)
+	chip8.SetScale(5)
+	chip8.SetScale(-1)
+
+	assert.Equal(t, []int{3, 5, 1}, spy.scales)
+	assert.Equal(t, 1, chip8.Scale())
+}
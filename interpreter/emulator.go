@@ -0,0 +1,147 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrRunning is returned by Emulator.Start when the emulator is already
+// running.
+var ErrRunning = errors.New("emulator already running")
+
+// Input is implemented by frontends that read the keypad. Poll is called
+// once before every frame and returns which hex keys are held.
+type Input interface {
+	Poll() [16]bool
+}
+
+// Emulator runs an interpreter together with its frontends: every 60Hz
+// frame it polls the Input, runs the frame's instructions, ticks the timers
+// and presents the Display, with the Sound started and stopped by the sound
+// timer. Frontends only implement the three interfaces.
+type Emulator struct {
+	c     *chip8
+	input Input
+	keys  [16]bool // Keys held at the last poll
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// NewEmulator returns an emulator presenting on d, beeping on s and reading
+// keys from in. Any of them may be nil.
+func NewEmulator(d Display, s Sound, in Input) *Emulator {
+	e := &Emulator{c: newChip8(DefaultMemorySize), input: in}
+	e.c.SetDisplay(d)
+	e.c.SetSound(s)
+	return e
+}
+
+// Interpreter returns the interpreter the emulator runs, for loading a ROM
+// and setting quirks, the clock speed and other options. It must not be
+// used while the emulator is running.
+func (e *Emulator) Interpreter() Machine {
+	return e.c
+}
+
+// SetScale sets how big the Display draws each CHIP-8 pixel if it is a
+// ScaledDisplay, see the interpreter's SetScale.
+func (e *Emulator) SetScale(n int) {
+	e.c.SetScale(n)
+}
+
+// LoadRomFromFile loads the ROM at path into the interpreter.
+func (e *Emulator) LoadRomFromFile(path string) (int, error) {
+	return e.c.LoadRomFromFile(path)
+}
+
+// Start runs the emulator in the background until Stop is called, ctx is
+// cancelled or an instruction fails.
+func (e *Emulator) Start(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done != nil {
+		return ErrRunning
+	}
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+	e.err = nil
+	go e.run(ctx, e.done)
+	return nil
+}
+
+// Stop stops a running emulator, waits for the current frame to finish and
+// returns the error of the instruction that ended the run, or nil if it was
+// still running or ended through ctx.
+func (e *Emulator) Stop() error {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cancel, e.done = nil, nil
+	return e.err
+}
+
+// Wait blocks until the run ends through ctx or a failing instruction and
+// returns what Stop would.
+func (e *Emulator) Wait() error {
+	e.mu.Lock()
+	done := e.done
+	e.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	<-done
+	return e.Stop()
+}
+
+func (e *Emulator) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	next := e.c.clock.Now().Add(TimerPeriod)
+	for {
+		e.pollInput()
+		if err := e.c.RunFrame(); err != nil {
+			e.finish(err)
+			return
+		}
+		next = e.c.waitFrame(next)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (e *Emulator) finish(err error) {
+	e.mu.Lock()
+	e.err = err
+	e.mu.Unlock()
+}
+
+// pollInput presses and releases keys that changed since the last poll.
+func (e *Emulator) pollInput() {
+	if e.input == nil {
+		return
+	}
+	keys := e.input.Poll()
+	for k, down := range keys {
+		if down == e.keys[k] {
+			continue
+		}
+		if down {
+			e.c.KeyDown(uint8(k))
+		} else {
+			e.c.KeyUp(uint8(k))
+		}
+	}
+	e.keys = keys
+}
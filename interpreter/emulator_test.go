@@ -0,0 +1,105 @@
+package interpreter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// chanDisplay hands frames drawn on the emulator goroutine to the test.
+type chanDisplay chan [][]byte
+
+func (d chanDisplay) Draw(frame [][]byte) {
+	select {
+	case d <- frame:
+	default:
+	}
+}
+
+type spyInput struct {
+	mu   sync.Mutex
+	keys [16]bool
+}
+
+func (in *spyInput) Poll() [16]bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.keys
+}
+
+func (in *spyInput) press(key uint8) {
+	in.mu.Lock()
+	in.keys[key] = true
+	in.mu.Unlock()
+}
+
+func TestEmulator(t *testing.T) {
+	display := make(chanDisplay, 1)
+	sound := &spySound{}
+	input := &spyInput{}
+	e := NewEmulator(display, sound, input)
+	c := e.Interpreter()
+	c.SetClockSpeed(600)
+	// LD V0, K; LD F, V0; DRW V1, V1, 5; LD ST, V0; JP 0x208
+	_, err := c.LoadRomBytes([]byte{0xF0, 0x0A, 0xF0, 0x29, 0xD1, 0x15, 0xF0, 0x18, 0x12, 0x08})
+	assert.NoError(t, err)
+
+	assert.NoError(t, e.Start(context.Background()))
+	assert.ErrorIs(t, e.Start(context.Background()), ErrRunning)
+	input.press(0x8)
+
+	// The glyph for 8 starts with a full row
+	deadline := time.After(2 * time.Second)
+	for drawn := false; !drawn; {
+		select {
+		case frame := <-display:
+			drawn = frame[0][0] == 1 && frame[0][3] == 1
+		case <-deadline:
+			t.Fatal("the key press never reached the screen")
+		}
+	}
+	assert.NoError(t, e.Stop())
+
+	// Stopped, so the interpreter and spies are safe to read
+	assert.Equal(t, byte(0x8), c.Registers()[0])
+	assert.Equal(t, 1, sound.starts)
+}
+
+func TestEmulatorStopsOnError(t *testing.T) {
+	e := NewEmulator(nil, nil, nil)
+	_, err := e.Interpreter().LoadRomBytes([]byte{0x00, 0x01}) // SYS 0x001
+
+	assert.NoError(t, err)
+	assert.NoError(t, e.Start(context.Background()))
+	err = e.Wait()
+
+	var uerr *UnknownOpcodeError
+	assert.ErrorAs(t, err, &uerr)
+	assert.NoError(t, e.Stop(), "already stopped")
+}
+
+func TestEmulatorContext(t *testing.T) {
+	e := NewEmulator(nil, nil, nil)
+	e.Interpreter().LoadRomBytes([]byte{0x12, 0x00}) // JP 0x200
+	ctx, cancel := context.WithCancel(context.Background())
+
+	assert.NoError(t, e.Start(ctx))
+	cancel()
+
+	assert.NoError(t, e.Wait())
+	assert.NoError(t, e.Start(context.Background()), "restartable")
+	assert.NoError(t, e.Stop())
+}
+
+func TestEmulatorSetScale(t *testing.T) {
+	spy := &scaledSpy{}
+	e := NewEmulator(spy, nil, nil)
+
+	e.SetScale(4)
+
+	assert.Equal(t, []int{1, 4}, spy.scales)
+	assert.Equal(t, 4, e.Interpreter().Scale())
+}
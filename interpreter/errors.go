@@ -0,0 +1,101 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrHalt is returned when HaltOnInfiniteLoop is set and a 1nnn jump targets
+// its own address, the idiom most ROMs use to stop.
+var ErrHalt = errors.New("halted on infinite loop")
+
+// ErrStepOverLimit is returned when StepOver gives up waiting for a
+// subroutine to return.
+var ErrStepOverLimit = errors.New("subroutine did not return within step over limit")
+
+// ErrRomTooLarge is returned when a ROM does not fit between the entry point
+// and the end of memory.
+var ErrRomTooLarge = errors.New("ROM too large for memory")
+
+// ErrLoadOutOfRange is returned when LoadAt is given data that would run
+// past the end of memory.
+var ErrLoadOutOfRange = errors.New("data does not fit in memory")
+
+// ErrStackOverflow is returned by Push, and wrapped in a StackError by
+// CALL, when all 16 stack cells are in use.
+var ErrStackOverflow = errors.New("stack overflow")
+
+// ErrStackUnderflow is wrapped in a StackError when RET finds the stack
+// empty.
+var ErrStackUnderflow = errors.New("stack underflow")
+
+// ErrProgramEnd is returned when control flow runs past the end of the
+// loaded ROM into zeroed memory and fetches 0000.
+var ErrProgramEnd = errors.New("program ran past the end of the ROM")
+
+// ErrExit is returned when the program executes the SUPER-CHIP 00FD EXIT,
+// asking the interpreter to shut down.
+var ErrExit = errors.New("program exited")
+
+// UnknownOpcodeError is returned when the interpreter decodes an opcode it
+// does not implement. PC is the address the opcode was fetched from.
+type UnknownOpcodeError struct {
+	Opcode uint16
+	PC     uint16
+}
+
+func (e *UnknownOpcodeError) Error() string {
+	return fmt.Sprintf("Unknown opcode: 0x%04X at 0x%04X", e.Opcode, e.PC)
+}
+
+// StackError is returned when the CALL or RET at PC overflows or underflows
+// the stack. Err is ErrStackOverflow or ErrStackUnderflow, so errors.Is
+// matches either.
+type StackError struct {
+	Err error
+	PC  uint16
+}
+
+func (e *StackError) Error() string {
+	return fmt.Sprintf("%s at 0x%04X", e.Err, e.PC)
+}
+
+func (e *StackError) Unwrap() error {
+	return e.Err
+}
+
+// AddressError is returned when an instruction at PC refers to an address
+// beyond the end of memory. Addr is an int as it may lie just past the end of
+// a 64KB memory, which no uint16 can hold.
+type AddressError struct {
+	Addr int
+	PC   uint16
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("Address 0x%04X out of range at 0x%04X", e.Addr, e.PC)
+}
+
+// PanicError is returned by SafeStep when stepping the instruction at PC
+// panicked. Opcode holds the bytes at PC, 0 where they lie past the end of
+// memory, and Value what the panic was called with.
+type PanicError struct {
+	Value  interface{}
+	Opcode uint16
+	PC     uint16
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Panic executing 0x%04X at 0x%04X: %v", e.Opcode, e.PC, e.Value)
+}
+
+// ProtectedWriteError is returned when ProtectReservedMemory is set and the
+// instruction at PC stores to Addr in the interpreter area below 0x200.
+type ProtectedWriteError struct {
+	Addr uint16
+	PC   uint16
+}
+
+func (e *ProtectedWriteError) Error() string {
+	return fmt.Sprintf("Write to reserved address 0x%04X at 0x%04X", e.Addr, e.PC)
+}
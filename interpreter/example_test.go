@@ -0,0 +1,64 @@
+package interpreter_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/l4rma/chip-8/interpreter"
+)
+
+// Example runs a program headlessly: with no Display or Sound installed
+// nothing is drawn or played, and RunCycles executes instructions without
+// waiting on the clock.
+func Example() {
+	c := interpreter.NewChip8()
+	c.Seed(1) // Make RND repeatable
+	rom := []byte{
+		0x60, 0x05, // LD V0, 5
+		0xC1, 0x0F, // RND V1, 0x0F
+		0xF2, 0x0A, // LD V2, K
+		0x82, 0x04, // ADD V2, V0
+		0x12, 0x08, // JP 0x208
+	}
+	if _, err := c.LoadRomBytes(rom); err != nil {
+		log.Fatal(err)
+	}
+
+	// LD V2, K repeats until a key is down
+	if err := c.RunCycles(10); err != nil {
+		log.Fatal(err)
+	}
+	c.KeyDown(0x7)
+	if err := c.RunCycles(2); err != nil {
+		log.Fatal(err)
+	}
+
+	v := c.Registers()
+	fmt.Printf("V0=%d V1=%d V2=%d\n", v[0], v[1], v[2])
+	// Output: V0=5 V1=1 V2=12
+}
+
+// ExampleController runs a machine on the controller's goroutine, loading a
+// ROM with a Command and reading the result back from the Updates.
+func ExampleController() {
+	ctl, err := interpreter.NewController(interpreter.NewChip8())
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ctl.Start(ctx)
+
+	// LD V0, 5; JP 0x202
+	ctl.Commands() <- interpreter.Command{Kind: interpreter.CmdLoad, ROM: []byte{0x60, 0x05, 0x12, 0x02}}
+	for u := range ctl.Updates() {
+		if u.V[0] == 5 {
+			fmt.Printf("V0=%d at 0x%03X\n", u.V[0], u.PC)
+			break
+		}
+	}
+	cancel()
+	for range ctl.Updates() {
+	}
+	// Output: V0=5 at 0x202
+}
@@ -1,5 +1,20 @@
 package interpreter
 
+import "fmt"
+
+// Fonts are loaded into the interpreter area at these addresses by default.
+// Fx29 and Fx30 point I at a digit's glyph in them.
+const (
+	FontBase      = 0x50 // 16 glyphs of FontHeight bytes
+	LargeFontBase = 0xA0 // 16 glyphs of LargeFontHeight bytes
+)
+
+// Glyph sizes in bytes, one byte per 8 pixel row.
+const (
+	FontHeight      = 5
+	LargeFontHeight = 10
+)
+
 var FontSet = []byte{
 	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
 	0x20, 0x60, 0x20, 0x20, 0x70, // 1
@@ -18,3 +33,57 @@ var FontSet = []byte{
 	0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
 	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 }
+
+// LargeFontSet is the SUPER-CHIP 8x10 font used by Fx30, with Octo's A-F
+// added to the original 0-9.
+var LargeFontSet = []byte{
+	0xFF, 0xFF, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, // 0
+	0x18, 0x78, 0x78, 0x18, 0x18, 0x18, 0x18, 0x18, 0xFF, 0xFF, // 1
+	0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // 2
+	0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 3
+	0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0x03, 0x03, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 5
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 6
+	0xFF, 0xFF, 0x03, 0x03, 0x06, 0x0C, 0x18, 0x18, 0x18, 0x18, // 7
+	0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 8
+	0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 9
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0, // F
+}
+
+// SetFont loads a font of 16 glyphs, FontHeight bytes each, at base and
+// points Fx29 at it.
+func (c *chip8) SetFont(data []byte, base uint16) error {
+	if err := c.loadFont(data, base, FontHeight); err != nil {
+		return err
+	}
+	c.font = append([]byte(nil), data...)
+	c.fontBase = base
+	return nil
+}
+
+// SetLargeFont loads a font of 16 glyphs, LargeFontHeight bytes each, at
+// base and points Fx30 at it.
+func (c *chip8) SetLargeFont(data []byte, base uint16) error {
+	if err := c.loadFont(data, base, LargeFontHeight); err != nil {
+		return err
+	}
+	c.largeFont = append([]byte(nil), data...)
+	c.largeFontBase = base
+	return nil
+}
+
+func (c *chip8) loadFont(data []byte, base uint16, height int) error {
+	if len(data) != 16*height {
+		return fmt.Errorf("font has %d bytes, want 16 glyphs of %d", len(data), height)
+	}
+	if int(base)+len(data) > len(c.memory) {
+		return fmt.Errorf("font at 0x%04X does not fit in memory", base)
+	}
+	_, err := c.LoadBytes(int(base), data)
+	return err
+}
@@ -0,0 +1,98 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFontsLoaded(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	assert.Equal(t, FontSet, chip8.memory[FontBase:FontBase+len(FontSet)])
+	assert.Equal(t, LargeFontSet, chip8.memory[LargeFontBase:LargeFontBase+len(LargeFontSet)])
+	assert.LessOrEqual(t, LargeFontBase+len(LargeFontSet), programStart)
+}
+
+func TestFx29SmallFont(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xF1, 0x29, 0xF2, 0x29} // LD F, V1; LD F, V2
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.I = 0x300 // Fx29 sets I rather than adding to it
+	chip8.V[1] = 0x0A
+	chip8.V[2] = 0x13 // Only the low nibble counts
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(FontBase+0xA*FontHeight), chip8.I)
+	assert.Equal(t, []byte{0xF0, 0x90, 0xF0, 0x90, 0x90}, chip8.memory[chip8.I:chip8.I+FontHeight])
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(FontBase+0x3*FontHeight), chip8.I)
+}
+
+func TestDrawFontDigit(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.ProtectReservedMemory = true // Reading the font area is still fine
+	// LD V0, 7; LD F, V0; LD V1, 10; LD V2, 3; DRW V1, V2, 5
+	testBytes := []byte{0x60, 0x07, 0xF0, 0x29, 0x61, 0x0A, 0x62, 0x03, 0xD1, 0x25}
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.NoError(t, chip8.RunCycles(5))
+
+	want := []string{
+		"####",
+		"   #",
+		"  # ",
+		" #  ",
+		" #  ",
+	}
+	for row, line := range want {
+		var got strings.Builder
+		for x := 0; x < 4; x++ {
+			if chip8.Pixel(10+x, 3+row) {
+				got.WriteByte('#')
+			} else {
+				got.WriteByte(' ')
+			}
+		}
+		assert.Equal(t, line, got.String(), "row %d", row)
+	}
+	assert.Equal(t, byte(0), chip8.V[0xF])
+}
+
+func TestFx30LargeFont(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xF1, 0x30} // LD HF, V1
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[1] = 0x7
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(LargeFontBase+0x7*LargeFontHeight), chip8.I)
+	assert.Equal(t, LargeFontSet[70:80], chip8.memory[chip8.I:chip8.I+LargeFontHeight])
+}
+
+func TestSetFont(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	font := make([]byte, 16*FontHeight)
+	for i := range font {
+		font[i] = byte(i)
+	}
+	assert.NoError(t, chip8.SetFont(font, 0x000))
+	testBytes := []byte{0xF1, 0x29} // LD F, V1
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[1] = 0x2
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x000+2*FontHeight), chip8.I)
+	assert.Equal(t, byte(10), chip8.memory[chip8.I])
+}
+
+func TestSetFontErrors(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	assert.Error(t, chip8.SetFont(FontSet[:40], FontBase))
+	assert.Error(t, chip8.SetLargeFont(FontSet, LargeFontBase))
+	assert.Error(t, chip8.SetFont(FontSet, 0x0FF0))
+	assert.NoError(t, chip8.SetLargeFont(LargeFontSet, 0x0F00))
+}
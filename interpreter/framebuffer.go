@@ -0,0 +1,66 @@
+//go:build !bytedisplay
+
+package interpreter
+
+// framebuffer holds both XO-CHIP planes at high resolution as bitsets: one
+// bit per pixel, 64 pixels to a word with the leftmost in the high bit.
+// Build with -tags bytedisplay for the one byte per pixel framebuffer_bytes.go
+// instead, to compare the two with BenchmarkDraw and BenchmarkClear.
+type framebuffer [2][HighResHeight][HighResWidth / 64]uint64
+
+// bit returns the word index and bit of column x.
+func bit(x int) (int, uint64) {
+	return x >> 6, 1 << (63 - uint(x&63))
+}
+
+// at returns the color index of the pixel at (x, y): bit 0 for plane 0 and
+// bit 1 for plane 1.
+func (f *framebuffer) at(x, y int) byte {
+	w, b := bit(x)
+	var color byte
+	if f[0][y][w]&b != 0 {
+		color |= 1
+	}
+	if f[1][y][w]&b != 0 {
+		color |= 2
+	}
+	return color
+}
+
+// lit reports whether the pixel at (x, y) is on in any plane.
+func (f *framebuffer) lit(x, y int) bool {
+	w, b := bit(x)
+	return (f[0][y][w]|f[1][y][w])&b != 0
+}
+
+// set turns the pixel at (x, y) on or off in planes.
+func (f *framebuffer) set(x, y int, planes byte, on bool) {
+	w, b := bit(x)
+	for p := range f {
+		if planes&(1<<p) == 0 {
+			continue
+		}
+		if on {
+			f[p][y][w] |= b
+		} else {
+			f[p][y][w] &^= b
+		}
+	}
+}
+
+// flip toggles the pixel at (x, y) in plane, reporting whether it was on.
+func (f *framebuffer) flip(x, y, plane int) bool {
+	w, b := bit(x)
+	was := f[plane][y][w]&b != 0
+	f[plane][y][w] ^= b
+	return was
+}
+
+// clear turns every pixel off in planes.
+func (f *framebuffer) clear(planes byte) {
+	for p := range f {
+		if planes&(1<<p) != 0 {
+			f[p] = [HighResHeight][HighResWidth / 64]uint64{}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build bytedisplay
+
+package interpreter
+
+// framebuffer holds both XO-CHIP planes at high resolution, one byte per
+// pixel with bit 0 for plane 0 and bit 1 for plane 1. It is the original
+// representation, kept for comparison with the bitsets of framebuffer.go.
+type framebuffer [HighResHeight][HighResWidth]byte
+
+// at returns the color index of the pixel at (x, y).
+func (f *framebuffer) at(x, y int) byte {
+	return f[y][x]
+}
+
+// lit reports whether the pixel at (x, y) is on in any plane.
+func (f *framebuffer) lit(x, y int) bool {
+	return f[y][x] != 0
+}
+
+// set turns the pixel at (x, y) on or off in planes.
+func (f *framebuffer) set(x, y int, planes byte, on bool) {
+	if on {
+		f[y][x] |= planes
+	} else {
+		f[y][x] &^= planes
+	}
+}
+
+// flip toggles the pixel at (x, y) in plane, reporting whether it was on.
+func (f *framebuffer) flip(x, y, plane int) bool {
+	mask := byte(1) << plane
+	was := f[y][x]&mask != 0
+	f[y][x] ^= mask
+	return was
+}
+
+// clear turns every pixel off in planes.
+func (f *framebuffer) clear(planes byte) {
+	for y := range f {
+		for x := range f[y] {
+			f[y][x] &^= planes
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package interpreter
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata ROMs and golden frames")
+
+// goldenROMs are test ROMs that draw their results. Each one is run
+// headlessly for at most cycles instructions or until it halts, and its
+// final frame compared with testdata/<name>.golden. Community test ROMs
+// (BC_test, corax89's opcode test, Timendus' suite) can be dropped into
+// testdata and listed here the same way.
+var goldenROMs = []struct {
+	name   string
+	quirks Quirks
+	cycles int
+}{
+	{"selftest", Quirks{}, 10000},
+}
+
+func TestGoldenFrames(t *testing.T) {
+	for _, rom := range goldenROMs {
+		t.Run(rom.name, func(t *testing.T) {
+			frame, err := runTestROM("testdata/"+rom.name+".ch8", rom.quirks, rom.cycles)
+			if !assert.NoError(t, err) {
+				return
+			}
+			golden := "testdata/" + rom.name + ".golden"
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, []byte(formatFrame(frame)), 0644))
+			}
+			want, err := os.ReadFile(golden)
+			if assert.NoError(t, err) {
+				assert.Equal(t, string(want), formatFrame(frame))
+			}
+		})
+	}
+}
+
+// TestGoldenSelfTestSource keeps selftest.ch8 in step with its source.
+func TestGoldenSelfTestSource(t *testing.T) {
+	src, err := os.ReadFile("testdata/selftest.asm")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rom, err := Assemble(string(src))
+	if !assert.NoError(t, err) {
+		return
+	}
+	if *update {
+		assert.NoError(t, os.WriteFile("testdata/selftest.ch8", rom, 0644))
+	}
+	want, err := os.ReadFile("testdata/selftest.ch8")
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, rom)
+	}
+}
+
+// runTestROM loads the ROM at path with the font and runs it until it jumps
+// to itself or cycles instructions have executed, returning the frame.
+func runTestROM(path string, quirks Quirks, cycles int) ([][]bool, error) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.Quirks = quirks
+	chip8.HaltOnInfiniteLoop = true
+	if _, err := chip8.LoadRomFromFile(path); err != nil {
+		return nil, err
+	}
+	if err := chip8.RunCycles(cycles); err != nil && !errors.Is(err, ErrHalt) {
+		return nil, err
+	}
+	return chip8.Framebuffer(), nil
+}
+
+// formatFrame renders a frame as one line per row, '#' for lit pixels.
+func formatFrame(frame [][]bool) string {
+	var b strings.Builder
+	for _, row := range frame {
+		for _, on := range row {
+			if on {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
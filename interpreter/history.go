@@ -0,0 +1,120 @@
+package interpreter
+
+import "errors"
+
+// ErrNoHistory is returned by StepBack when there is no earlier step to go
+// back to.
+var ErrNoHistory = errors.New("no step history")
+
+// snapshot is the machine state StepBack restores: everything an
+// instruction can change. Cxkk's generator is rewound too once Seed has
+// made it repeatable; the math/rand package generator used otherwise
+// cannot be, so StepBack over an unseeded RND may draw a different value.
+type snapshot struct {
+	memory       []byte
+	V            [0x10]byte
+	I            uint16
+	PC           uint16
+	SP           byte
+	stack        [0x10]uint16
+	display      framebuffer
+	hires        bool
+	planes       byte
+	collision    bool
+	vblank       bool
+	latched      uint16
+	delayTimer   byte
+	soundTimer   byte
+	audioPattern [16]byte
+	hasPattern   bool
+	rplFlags     [8]byte
+	cycles       uint64
+	seed         int64
+	rngDraws     uint64
+}
+
+// history is a ring buffer of the snapshots taken before each step, the
+// newest at start+n-1.
+type history struct {
+	snaps []snapshot
+	start int
+	n     int
+}
+
+// SetHistoryDepth keeps the state before each of the last depth steps so
+// StepBack can undo them, 256 being plenty for a debugger. A depth of 0,
+// the default, turns history off; changing the depth discards it.
+func (c *chip8) SetHistoryDepth(depth int) {
+	c.history = history{}
+	if depth > 0 {
+		c.history.snaps = make([]snapshot, depth)
+	}
+}
+
+// StepBack undoes the last step, restoring the state from before it. It
+// returns ErrNoHistory once the recorded steps run out.
+func (c *chip8) StepBack() error {
+	h := &c.history
+	if h.n == 0 {
+		return ErrNoHistory
+	}
+	h.n--
+	c.restore(&h.snaps[(h.start+h.n)%len(h.snaps)])
+	c.dirty = true
+	return nil
+}
+
+// pushHistory snapshots the state before a step, overwriting the oldest
+// snapshot once the buffer is full.
+func (c *chip8) pushHistory() {
+	h := &c.history
+	var s *snapshot
+	if h.n < len(h.snaps) {
+		s = &h.snaps[(h.start+h.n)%len(h.snaps)]
+		h.n++
+	} else {
+		s = &h.snaps[h.start]
+		h.start = (h.start + 1) % len(h.snaps)
+	}
+	c.save(s)
+}
+
+// dropHistory discards the newest snapshot, for a step that failed.
+func (c *chip8) dropHistory() {
+	if c.history.n > 0 {
+		c.history.n--
+	}
+}
+
+func (c *chip8) save(s *snapshot) {
+	// Reuse the memory copy of the snapshot this one overwrites
+	if len(s.memory) != len(c.memory) {
+		s.memory = make([]byte, len(c.memory))
+	}
+	copy(s.memory, c.memory)
+	s.V, s.I, s.PC, s.SP, s.stack = c.V, c.I, c.PC, c.SP, c.stack
+	s.display, s.hires, s.planes = c.display, c.hires, c.planes
+	s.collision, s.vblank, s.latched = c.collision, c.vblank, c.latched
+	s.delayTimer, s.soundTimer = c.delayTimer, c.soundTimer
+	s.audioPattern, s.hasPattern, s.rplFlags = c.audioPattern, c.hasPattern, c.rplFlags
+	s.cycles = c.cycles
+	s.seed, s.rngDraws = c.seed, c.rngDraws
+}
+
+func (c *chip8) restore(s *snapshot) {
+	copy(c.memory, s.memory)
+	c.V, c.I, c.PC, c.SP, c.stack = s.V, s.I, s.PC, s.SP, s.stack
+	c.display, c.hires, c.planes = s.display, s.hires, s.planes
+	c.collision, c.vblank, c.latched = s.collision, s.vblank, s.latched
+	c.delayTimer, c.soundTimer = s.delayTimer, s.soundTimer
+	c.audioPattern, c.hasPattern, c.rplFlags = s.audioPattern, s.hasPattern, s.rplFlags
+	c.cycles = s.cycles
+	if c.rng != nil && (s.seed != c.seed || s.rngDraws != c.rngDraws) {
+		// A rand.Rand cannot be copied, so reseed and draw up to the
+		// snapshot again
+		c.Seed(s.seed)
+		for c.rngDraws < s.rngDraws {
+			c.random()
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepBack(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetHistoryDepth(256)
+	// LD V0, 5; LD I, 0x300; LD B, V0; ADD V0, 1; CALL 0x20C; SYS; RET
+	chip8.LoadBytes(0x200, []byte{
+		0x60, 0x05, 0xA3, 0x00, 0xF0, 0x33, 0x70, 0x01, 0x22, 0x0C, 0x00, 0x00, 0x00, 0xEE,
+	})
+
+	var states []snapshot
+	for i := 0; i < 5; i++ {
+		var s snapshot
+		chip8.save(&s)
+		states = append(states, s)
+		assert.NoError(t, chip8.Step())
+	}
+	assert.Equal(t, uint16(0x20C), chip8.PC)
+	assert.Equal(t, byte(1), chip8.SP)
+
+	assert.NoError(t, chip8.StepBack())
+	var s snapshot
+	chip8.save(&s)
+	assert.Equal(t, states[4], s)
+
+	assert.NoError(t, chip8.StepBack())
+	chip8.save(&s)
+	assert.Equal(t, states[3], s)
+	assert.Equal(t, uint16(0x206), chip8.PC)
+	assert.Equal(t, byte(5), chip8.V[0])
+	assert.Equal(t, []byte{0, 0, 5}, chip8.memory[0x300:0x303], "BCD store kept")
+
+	// Stepping forward again replays the same instructions
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, uint16(0x20C), chip8.PC)
+	assert.Equal(t, byte(6), chip8.V[0])
+}
+
+func TestStepBackRestoresMemory(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetHistoryDepth(4)
+	chip8.V[0] = 123
+	// LD I, 0x300; LD B, V0
+	chip8.LoadBytes(0x200, []byte{0xA3, 0x00, 0xF0, 0x33})
+
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.Equal(t, []byte{1, 2, 3}, chip8.memory[0x300:0x303])
+
+	assert.NoError(t, chip8.StepBack())
+	assert.Equal(t, []byte{0, 0, 0}, chip8.memory[0x300:0x303])
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.Equal(t, uint64(1), chip8.Cycles())
+}
+
+func TestStepBackDepth(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetHistoryDepth(2)
+	// ADD V0, 1; JP 0x200
+	chip8.LoadBytes(0x200, []byte{0x70, 0x01, 0x12, 0x00})
+
+	assert.NoError(t, chip8.RunCycles(5))
+	assert.Equal(t, byte(3), chip8.V[0])
+
+	assert.NoError(t, chip8.StepBack())
+	assert.NoError(t, chip8.StepBack())
+	assert.Equal(t, byte(2), chip8.V[0])
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.ErrorIs(t, chip8.StepBack(), ErrNoHistory)
+}
+
+func TestStepBackSkipsFailedSteps(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetHistoryDepth(4)
+	// LD V0, 1; SYS 0x000
+	chip8.LoadBytes(0x200, []byte{0x60, 0x01, 0x00, 0x00})
+
+	assert.NoError(t, chip8.Step())
+	assert.Error(t, chip8.Step())
+
+	assert.NoError(t, chip8.StepBack())
+	assert.Equal(t, uint16(0x200), chip8.PC)
+	assert.ErrorIs(t, chip8.StepBack(), ErrNoHistory)
+}
+
+func TestStepBackOffByDefault(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+
+	assert.NoError(t, chip8.Step())
+	assert.ErrorIs(t, chip8.StepBack(), ErrNoHistory)
+}
+
+func TestStepBackRandom(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetHistoryDepth(16)
+	chip8.Seed(3)
+	chip8.LoadBytes(0x200, []byte{0xC0, 0xFF, 0xC1, 0xFF}) // RND V0, 0xFF; RND V1, 0xFF
+	assert.NoError(t, chip8.RunCycles(2))
+	v := chip8.V
+
+	assert.NoError(t, chip8.StepBack())
+	assert.NoError(t, chip8.StepBack())
+	assert.NoError(t, chip8.RunCycles(2))
+
+	assert.Equal(t, v, chip8.V, "RND draws the same values again")
+}
@@ -0,0 +1,128 @@
+package interpreter
+
+import "unicode"
+
+// DefaultKeyMap maps the conventional QWERTY layout onto the hex keypad:
+//
+//	1 2 3 4      1 2 3 C
+//	Q W E R  ->  4 5 6 D
+//	A S D F      7 8 9 E
+//	Z X C V      A 0 B F
+func DefaultKeyMap() map[rune]uint8 {
+	return map[rune]uint8{
+		'1': 0x1, '2': 0x2, '3': 0x3, '4': 0xC,
+		'q': 0x4, 'w': 0x5, 'e': 0x6, 'r': 0xD,
+		'a': 0x7, 's': 0x8, 'd': 0x9, 'f': 0xE,
+		'z': 0xA, 'x': 0x0, 'c': 0xB, 'v': 0xF,
+	}
+}
+
+// KeyDown presses hex key 0x0-0xF. Other values are ignored. With
+// BufferKeyPresses the press is also latched until the end of the frame, so
+// the ROM still sees a key released again before it got to look.
+func (c *chip8) KeyDown(key uint8) {
+	if key < 16 {
+		if c.keypad[key] == 0 {
+			c.emitKey(KeyEvent{key, true})
+		}
+		c.keypad[key] = 1
+		c.latched |= 1 << key
+		c.record(key, true)
+	}
+}
+
+// KeyUp releases hex key 0x0-0xF. Other values are ignored.
+func (c *chip8) KeyUp(key uint8) {
+	if key < 16 {
+		if c.keypad[key] == 1 {
+			c.emitKey(KeyEvent{key, false})
+		}
+		c.keypad[key] = 0
+		c.record(key, false)
+	}
+}
+
+// IsKeyPressed reports whether hex key 0x0-0xF is held down.
+func (c *chip8) IsKeyPressed(key uint8) bool {
+	return key < 16 && c.keypad[key] == 1
+}
+
+// KeyEvent is a hex key going down or coming back up.
+type KeyEvent struct {
+	Key  uint8
+	Down bool
+}
+
+// keyEventBuffer is how many KeyEvents wait for a slow reader before the
+// oldest are dropped.
+const keyEventBuffer = 64
+
+// KeyEvents returns a channel receiving a KeyEvent every time KeyDown or
+// KeyUp, or a Replay, changes the state of a key; pressing a key that is
+// already down sends nothing. Reset sends a key-up for every key still held.
+// Rather than holding up the interpreter when the channel's buffer is full,
+// the oldest event is dropped, so a reader that starts late still gets the
+// latest transitions. Every call returns the same channel.
+func (c *chip8) KeyEvents() <-chan KeyEvent {
+	return c.keyEvents
+}
+
+func (c *chip8) emitKey(e KeyEvent) {
+	select {
+	case c.keyEvents <- e:
+		return
+	default:
+	}
+	// Full: make room by dropping the oldest event
+	select {
+	case <-c.keyEvents:
+	default:
+	}
+	select {
+	case c.keyEvents <- e:
+	default:
+	}
+}
+
+// PressedKeys returns the hex keys currently held down, in ascending order.
+func (c *chip8) PressedKeys() []uint8 {
+	var keys []uint8
+	for k, v := range c.keypad {
+		if v == 1 {
+			keys = append(keys, uint8(k))
+		}
+	}
+	return keys
+}
+
+// keyHeld reports whether the ROM should see key as down: it is pressed, or
+// was tapped this frame and BufferKeyPresses is set.
+func (c *chip8) keyHeld(key uint8) bool {
+	return c.keypad[key] == 1 || c.BufferKeyPresses && c.latched&(1<<key) != 0
+}
+
+// SetKeyMap replaces the map used by KeyDownRune and KeyUpRune. Keys are
+// matched case-insensitively, so the map should use lower case runes.
+func (c *chip8) SetKeyMap(m map[rune]uint8) {
+	c.keyMap = m
+}
+
+// KeyDownRune presses the hex key mapped to r and reports whether r is
+// mapped at all.
+func (c *chip8) KeyDownRune(r rune) bool {
+	key, ok := c.keyMap[unicode.ToLower(r)]
+	if ok {
+		c.KeyDown(key)
+	}
+	return ok
+}
+
+// KeyUpRune releases the hex key mapped to r and reports whether r is mapped
+// at all.
+func (c *chip8) KeyUpRune(r rune) bool {
+	key, ok := c.keyMap[unicode.ToLower(r)]
+	if ok {
+		c.KeyUp(key)
+	}
+	return ok
+}
@@ -0,0 +1,231 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultKeyMap(t *testing.T) {
+	keys := DefaultKeyMap()
+
+	assert.Len(t, keys, 16)
+	assert.Equal(t, uint8(0x1), keys['1'])
+	assert.Equal(t, uint8(0xC), keys['4'])
+	assert.Equal(t, uint8(0x4), keys['q'])
+	assert.Equal(t, uint8(0x0), keys['x'])
+	assert.Equal(t, uint8(0xF), keys['v'])
+}
+
+func TestKeyDownUp(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	chip8.KeyDown(0xA)
+	assert.Equal(t, byte(1), chip8.keypad[0xA])
+	chip8.KeyUp(0xA)
+	assert.Equal(t, byte(0), chip8.keypad[0xA])
+
+	chip8.KeyDown(0x10)
+	assert.Equal(t, [16]byte{}, chip8.keypad)
+}
+
+func TestPressedKeys(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	assert.Empty(t, chip8.PressedKeys())
+
+	chip8.KeyDown(0xF)
+	chip8.KeyDown(0x3)
+	chip8.KeyDown(0x7)
+	chip8.KeyUp(0x7)
+
+	assert.Equal(t, []uint8{0x3, 0xF}, chip8.PressedKeys())
+}
+
+func TestIsKeyPressed(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	chip8.KeyDown(0xA)
+	assert.True(t, chip8.IsKeyPressed(0xA))
+	assert.False(t, chip8.IsKeyPressed(0xB))
+	assert.False(t, chip8.IsKeyPressed(0x1A))
+	chip8.KeyUp(0xA)
+	assert.False(t, chip8.IsKeyPressed(0xA))
+}
+
+func TestKeyEvents(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	events := chip8.KeyEvents()
+
+	chip8.KeyDown(0x5)
+	chip8.KeyDown(0x5) // Already down
+	chip8.KeyDown(0xC)
+	chip8.KeyUp(0x5)
+	chip8.KeyUp(0x5) // Already up
+	chip8.KeyUp(0xC)
+
+	var got []KeyEvent
+	for len(events) > 0 {
+		got = append(got, <-events)
+	}
+	assert.Equal(t, []KeyEvent{{0x5, true}, {0xC, true}, {0x5, false}, {0xC, false}}, got)
+	assert.Equal(t, events, chip8.KeyEvents())
+}
+
+func TestKeyEventsReset(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	events := chip8.KeyEvents()
+	chip8.KeyDown(0x3)
+	chip8.KeyDown(0x9)
+	<-events
+	<-events
+
+	chip8.Reset()
+
+	var got []KeyEvent
+	for len(events) > 0 {
+		got = append(got, <-events)
+	}
+	assert.Equal(t, []KeyEvent{{0x3, false}, {0x9, false}}, got)
+}
+
+func TestKeyEventsConcurrent(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	done := make(chan struct{})
+	go func() {
+		chip8.KeyDown(0x1)
+		close(done)
+	}()
+	events := chip8.KeyEvents()
+	<-done
+
+	assert.Equal(t, KeyEvent{0x1, true}, <-events)
+}
+
+func TestKeyEventsFullBuffer(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	events := chip8.KeyEvents()
+
+	for i := 0; i < keyEventBuffer; i++ {
+		chip8.KeyDown(0x1)
+		chip8.KeyUp(0x1)
+	}
+
+	assert.Len(t, events, keyEventBuffer, "the rest dropped without blocking")
+}
+
+func TestKeyEventsLateSubscriber(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	for i := 0; i < 100; i++ {
+		chip8.KeyDown(uint8(i % 4))
+		chip8.KeyUp(uint8(i % 4))
+	}
+	chip8.KeyDown(0x5)
+
+	events := chip8.KeyEvents()
+	var got []KeyEvent
+	for len(events) > 0 {
+		got = append(got, <-events)
+	}
+
+	if assert.Len(t, got, keyEventBuffer) {
+		assert.Equal(t, KeyEvent{0x5, true}, got[len(got)-1], "newest kept")
+		assert.Equal(t, KeyEvent{0x0, false}, got[0], "oldest dropped")
+	}
+}
+
+func TestKeyRunes(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	assert.True(t, chip8.KeyDownRune('Q'))
+	assert.Equal(t, byte(1), chip8.keypad[0x4])
+	assert.True(t, chip8.KeyUpRune('q'))
+	assert.Equal(t, byte(0), chip8.keypad[0x4])
+	assert.False(t, chip8.KeyDownRune('p'))
+
+	chip8.SetKeyMap(map[rune]uint8{'p': 0x4})
+	assert.True(t, chip8.KeyDownRune('p'))
+	assert.Equal(t, byte(1), chip8.keypad[0x4])
+	assert.False(t, chip8.KeyDownRune('q'))
+}
+
+func TestSkipKeyInvalidVx(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xE0, 0x9E, 0x00, 0x00, 0xE0, 0xA1} // SKP V0; -; SKNP V0
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.V[0] = 0xFF
+
+	assert.NotPanics(t, func() { chip8.Step() })
+	assert.Equal(t, uint16(0x202), chip8.PC)
+
+	chip8.KeyDown(0xF)
+	chip8.PC = 0x200
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x204), chip8.PC)
+
+	assert.NotPanics(t, func() { chip8.Step() })
+	assert.Equal(t, uint16(0x206), chip8.PC)
+}
+
+func TestWaitKey(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xF3, 0x0A} // LD V3, K
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.NoError(t, chip8.RunCycles(3))
+	assert.Equal(t, uint16(0x200), chip8.PC)
+
+	chip8.KeyDown(0xB)
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x202), chip8.PC)
+	assert.Equal(t, uint8(0xB), chip8.V[3])
+}
+
+func TestBufferKeyPressesFx0A(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.BufferKeyPresses = true
+	testBytes := []byte{0xF3, 0x0A, 0xF4, 0x0A} // LD V3, K; LD V4, K
+	chip8.LoadBytes(0x200, testBytes)
+
+	chip8.KeyDown(0x5)
+	chip8.KeyUp(0x5)
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint8(0x5), chip8.V[3])
+	assert.Equal(t, uint16(0x202), chip8.PC)
+
+	// The tap was used up by the first Fx0A
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func TestUnbufferedTapIsMissed(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xF3, 0x0A} // LD V3, K
+	chip8.LoadBytes(0x200, testBytes)
+
+	chip8.KeyDown(0x5)
+	chip8.KeyUp(0x5)
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestBufferKeyPressesLastsOneFrame(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.BufferKeyPresses = true
+	// LD V0, 7; SKP V0; JP 0x202
+	testBytes := []byte{0x60, 0x07, 0xE0, 0x9E, 0x12, 0x02}
+	chip8.LoadBytes(0x200, testBytes)
+	assert.NoError(t, chip8.Step())
+
+	chip8.KeyDown(0x7)
+	chip8.KeyUp(0x7)
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x206), chip8.PC)
+
+	chip8.PC = 0x202
+	assert.NoError(t, chip8.RunFrame())
+	chip8.PC = 0x202
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x204), chip8.PC)
+}
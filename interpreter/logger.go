@@ -0,0 +1,20 @@
+package interpreter
+
+// Logger receives the interpreter's diagnostic output. *log.Logger
+// satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+// SetLogger routes diagnostic output to l. The interpreter is silent until a
+// logger is set; a nil l silences it again.
+func (c *chip8) SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	c.logger = l
+}
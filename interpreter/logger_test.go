@@ -0,0 +1,60 @@
+package interpreter
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultLoggerIsSilent(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x62, 0x69, 0x00, 0xE0, 0x00, 0x00}
+	chip8.LoadBytes(0x200, testBytes)
+
+	assert.Error(t, chip8.Run())
+	chip8.MemoryDump(0x0000)
+
+	assert.Empty(t, buf.String())
+}
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetLogger(log.New(&buf, "", 0))
+
+	assert.Error(t, chip8.Step())
+
+	assert.Contains(t, buf.String(), "Unknown opcode: 0x0000")
+}
+
+func TestLogIgnoredSYS(t *testing.T) {
+	var buf bytes.Buffer
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetLogger(log.New(&buf, "", 0))
+	chip8.IgnoreSysCalls = true
+	chip8.LoadBytes(0x200, []byte{0x01, 0x23}) // SYS 0x123
+
+	assert.NoError(t, chip8.Step())
+
+	assert.Equal(t, "Ignoring SYS 0x123 at 0x0200\n", buf.String())
+}
+
+func TestMemoryDumpLogsState(t *testing.T) {
+	var buf bytes.Buffer
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetLogger(log.New(&buf, "", 0))
+	chip8.V[3] = 0x42
+
+	chip8.MemoryDump(0x00E0)
+
+	assert.Contains(t, buf.String(), "=== MEMORY DUMP ===\n")
+	assert.Contains(t, buf.String(), "V3=42")
+	assert.Contains(t, buf.String(), "Current opcode: 00E0")
+}
@@ -0,0 +1,120 @@
+package interpreter
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Machine is the interpreter's public behaviour, as returned by NewChip8,
+// for frontends that take the interpreter as a dependency and for tests
+// that stub it out. Quirks and the other options are reached through
+// Config.
+type Machine interface {
+	Config() *Options
+	Init() error
+	Reset()
+
+	LoadRom(r io.Reader) (int, error)
+	LoadRomBytes(rom []byte) (int, error)
+	LoadRomFS(fsys fs.FS, name string) (int, error)
+	LoadRomFromFile(path string) (int, error)
+	LoadRomInfo(r io.Reader) (RomInfo, error)
+	LoadRomSwapped(r io.Reader) (int, error)
+	LoadOctoCartridge(r io.Reader) (int, error)
+	LoadAt(addr uint16, data []byte) (int, error)
+	LoadBytes(o int, b []byte) (int, error)
+	EntryPoint() uint16
+	SetEntryPoint(addr uint16) error
+	SetFont(data []byte, base uint16) error
+	SetLargeFont(data []byte, base uint16) error
+
+	Step() error
+	SafeStep() error
+	StepInfo() (StepResult, error)
+	StepOver() error
+	StepBack() error
+	SetHistoryDepth(depth int)
+	Fetch() (uint16, error)
+	FetchInstruction() (uint16, error)
+	Execute(in Instruction) error
+	ExecuteOpcode(op uint16) (uint16, error)
+	RunCycles(n int) error
+	RunFrame() error
+	Run() error
+	RunContext(ctx context.Context) error
+	Pause()
+	Resume()
+	Paused() bool
+	ClockSpeed() int
+	SetClockSpeed(hz int)
+	SpeedMultiplier() float64
+	SetSpeedMultiplier(m float64)
+	SetClock(clk Clock)
+	Seed(seed int64)
+	Cycles() uint64
+	OpcodeStats() map[uint16]uint64
+
+	Registers() [16]byte
+	SetRegisters(v [16]byte)
+	IndexRegister() uint16
+	SetIndexRegister(addr uint16)
+	DelayTimer() byte
+	SetDelayTimer(v byte)
+	SoundTimer() byte
+	SetSoundTimer(v byte)
+	TickTimers(n int)
+	LastTimerTick() time.Time
+	Push(addr uint16) error
+	CallStack() []uint16
+	StackDepth() int
+	Snapshot() State
+	DumpState() string
+	MemoryDump(opcode uint16)
+	PrintMemory(index int)
+	HexDump(addr uint16, n int) string
+	PeekMemory(addr uint16, n int) []byte
+	DisassembleLoaded() []string
+	WatchMemory(addr uint16, f WatchFunc) func()
+	SetCodeWriteCallback(f CodeWriteFunc)
+	SetTraceFunc(f TraceFunc)
+	SetLogger(l Logger)
+
+	Dimensions() (w, h int)
+	Frame() [][]byte
+	Framebuffer() [][]bool
+	Intensity() [][]float32
+	Pixel(x, y int) bool
+	SetPixel(x, y int, on bool)
+	Collision() bool
+	LastDrawDirtyRegion() (x, y, w, h int)
+	MapDisplay(addr uint16) error
+	UnmapDisplay()
+	SetDisplay(d Display)
+	Scale() int
+	SetScale(n int)
+	SetPersistence(frames int)
+	SetFrameCallback(f func(frame [][]bool))
+	ScreenshotPBM(w io.Writer) error
+	ScreenshotPNG(w io.Writer) error
+
+	SetSound(s Sound)
+	SetSoundStateCallback(f func(playing bool))
+	SetAudioCallback(f AudioFunc)
+	SetSampleRate(hz int)
+
+	KeyDown(key uint8)
+	KeyUp(key uint8)
+	KeyDownRune(r rune) bool
+	KeyUpRune(r rune) bool
+	IsKeyPressed(key uint8) bool
+	PressedKeys() []uint8
+	KeyEvents() <-chan KeyEvent
+	SetKeyMap(m map[rune]uint8)
+	StartRecording()
+	StopRecording() []InputEvent
+	Replay(events []InputEvent)
+}
+
+var _ Machine = (*chip8)(nil)
@@ -0,0 +1,43 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubMachine implements Machine by embedding it, overriding only what a
+// test needs.
+type stubMachine struct {
+	Machine
+	steps int
+}
+
+func (m *stubMachine) Step() error {
+	m.steps++
+	return nil
+}
+
+// stepTwice stands in for frontend code written against Machine.
+func stepTwice(m Machine) error {
+	if err := m.Step(); err != nil {
+		return err
+	}
+	return m.Step()
+}
+
+func TestMachine(t *testing.T) {
+	var m Machine = NewChip8()
+	m.LoadRomBytes([]byte{0x60, 0x01, 0x70, 0x01}) // LD V0, 1; ADD V0, 1
+
+	assert.NoError(t, stepTwice(m))
+	assert.Equal(t, uint64(2), m.Cycles())
+	assert.Equal(t, byte(2), m.(*chip8).V[0])
+
+	m.Config().Quirks = SuperChipQuirks
+	assert.Equal(t, SuperChipQuirks, m.(*chip8).Quirks, "options set through Config")
+
+	stub := &stubMachine{}
+	assert.NoError(t, stepTwice(stub))
+	assert.Equal(t, 2, stub.steps)
+}
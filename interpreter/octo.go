@@ -0,0 +1,81 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrNoRom is returned by LoadOctoCartridge for a cartridge without a ROM.
+var ErrNoRom = errors.New("cartridge has no ROM")
+
+// OctoOptions are the options Octo stores alongside a program, as in the
+// "options" object of its cartridges and share links. Only the ones that
+// affect execution are kept.
+type OctoOptions struct {
+	TickRate        int  `json:"tickrate"`        // Instructions per 60Hz frame
+	ShiftQuirks     bool `json:"shiftQuirks"`     // 8xy6/8xyE shift Vx in place
+	LoadStoreQuirks bool `json:"loadStoreQuirks"` // Fx55/Fx65 leave I unchanged
+	VFOrderQuirks   bool `json:"vfOrderQuirks"`   // 8xy4-8xyE write VF before the result
+	ClipQuirks      bool `json:"clipQuirks"`      // Sprites clip at the edges
+	JumpQuirks      bool `json:"jumpQuirks"`      // Bxnn jumps to xnn + Vx
+	LogicQuirks     bool `json:"logicQuirks"`     // 8xy1/8xy2/8xy3 clear VF
+	VBlankQuirks    bool `json:"vBlankQuirks"`    // Dxyn waits for vblank
+}
+
+// ParseOctoOptions decodes an Octo options JSON object.
+func ParseOctoOptions(data []byte) (OctoOptions, error) {
+	var opts OctoOptions
+	err := json.Unmarshal(data, &opts)
+	return opts, err
+}
+
+// Quirks maps the options onto this interpreter's quirks.
+func (o OctoOptions) Quirks() Quirks {
+	q := Quirks{
+		ShiftUsesVy:      !o.ShiftQuirks,
+		JumpUsesVx:       o.JumpQuirks,
+		DisplayWait:      o.VBlankQuirks,
+		WrapSprites:      !o.ClipQuirks,
+		LogicOpsResetVF:  o.LogicQuirks,
+		FlagBeforeResult: o.VFOrderQuirks,
+	}
+	if o.LoadStoreQuirks {
+		q.LoadStoreIncrement = IndexIncrementNone
+	}
+	return q
+}
+
+// ClockSpeed is the instructions per second the tick rate comes to, 0 if
+// the options do not set it.
+func (o OctoOptions) ClockSpeed() int {
+	return o.TickRate * 60
+}
+
+// octoCartridge is the JSON LoadOctoCartridge reads.
+type octoCartridge struct {
+	Options OctoOptions `json:"options"`
+	ROM     []byte      `json:"rom"` // Base64, as encoding/json encodes []byte
+}
+
+// LoadOctoCartridge reads a JSON object holding Octo's "options" and the
+// assembled program base64 encoded in "rom", loads the ROM at 0x200 and
+// sets Quirks and the clock speed from the options.
+func (c *chip8) LoadOctoCartridge(r io.Reader) (int, error) {
+	var cart octoCartridge
+	if err := json.NewDecoder(r).Decode(&cart); err != nil {
+		return 0, err
+	}
+	if len(cart.ROM) == 0 {
+		return 0, ErrNoRom
+	}
+	n, err := c.LoadRomBytes(cart.ROM)
+	if err != nil {
+		return n, err
+	}
+	c.Quirks = cart.Options.Quirks()
+	if hz := cart.Options.ClockSpeed(); hz > 0 {
+		c.SetClockSpeed(hz)
+	}
+	return n, nil
+}
@@ -0,0 +1,64 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOctoOptions(t *testing.T) {
+	opts, err := ParseOctoOptions([]byte(`{
+		"tickrate": 20,
+		"fillColor": "#FFCC00",
+		"shiftQuirks": true,
+		"loadStoreQuirks": true,
+		"vfOrderQuirks": false,
+		"clipQuirks": true,
+		"jumpQuirks": true,
+		"logicQuirks": false,
+		"vBlankQuirks": false,
+		"screenRotation": 0
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1200, opts.ClockSpeed())
+	assert.Equal(t, Quirks{
+		LoadStoreIncrement: IndexIncrementNone,
+		JumpUsesVx:         true,
+	}, opts.Quirks(), "SUPER-CHIP settings")
+}
+
+func TestOctoOptionsDefaults(t *testing.T) {
+	opts, err := ParseOctoOptions([]byte(`{"logicQuirks": true, "vBlankQuirks": true, "vfOrderQuirks": true}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, opts.ClockSpeed())
+	assert.Equal(t, Quirks{
+		ShiftUsesVy:      true,
+		DisplayWait:      true,
+		WrapSprites:      true,
+		LogicOpsResetVF:  true,
+		FlagBeforeResult: true,
+	}, opts.Quirks())
+
+	_, err = ParseOctoOptions([]byte(`{"tickrate": "fast"}`))
+	assert.Error(t, err)
+}
+
+func TestLoadOctoCartridge(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// "YmkSAA==" is LD V2, 0x69; JP 0x200
+	cart := `{"options": {"tickrate": 7, "jumpQuirks": true}, "rom": "YmkSAA=="}`
+
+	n, err := chip8.LoadOctoCartridge(strings.NewReader(cart))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []byte{0x62, 0x69, 0x12, 0x00}, chip8.memory[0x200:0x204])
+	assert.True(t, chip8.Quirks.JumpUsesVx)
+	assert.Equal(t, 420, chip8.ClockSpeed())
+
+	_, err = chip8.LoadOctoCartridge(strings.NewReader(`{"options": {}}`))
+	assert.ErrorIs(t, err, ErrNoRom)
+}
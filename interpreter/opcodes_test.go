@@ -0,0 +1,202 @@
+package interpreter
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOpcodes executes one opcode from 0x200 per case, after setup has
+// prepared the machine, and checks its effect on registers, memory and PC.
+func TestOpcodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		op    uint16
+		setup func(c *chip8)
+		check func(t *testing.T, c *chip8)
+	}{
+		{"Annn LD I", 0xA123, nil, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x123), c.I)
+			assert.Equal(t, uint16(0x202), c.PC)
+		}},
+		{"Bnnn JP V0", 0xB300, func(c *chip8) {
+			c.V[0], c.V[3] = 0x10, 0x40
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x310), c.PC)
+		}},
+		{"Bxnn JP Vx", 0xB320, func(c *chip8) {
+			c.Quirks.JumpUsesVx = true
+			c.V[0], c.V[3] = 0x10, 0x40
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x360), c.PC)
+		}},
+		{"Cxkk RND", 0xC30F, func(c *chip8) {
+			c.Seed(7)
+		}, func(t *testing.T, c *chip8) {
+			want := byte(rand.New(rand.NewSource(7)).Intn(256)) & 0x0F
+			assert.Equal(t, want, c.V[3])
+			assert.Equal(t, uint16(0x202), c.PC)
+		}},
+		{"Cxkk RND mask 0", 0xC300, func(c *chip8) {
+			c.V[3] = 0xFF
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, byte(0), c.V[3])
+		}},
+		{"Dxyn DRW", 0xD121, func(c *chip8) {
+			c.I = 0x300
+			c.memory[0x300] = 0xF0
+			c.V[1], c.V[2], c.V[0xF] = 2, 3, 1
+		}, func(t *testing.T, c *chip8) {
+			for x := 0; x < 8; x++ {
+				assert.Equal(t, x >= 2 && x < 6, c.Pixel(x, 3), "x %d", x)
+			}
+			assert.Equal(t, byte(0), c.V[0xF])
+			assert.Equal(t, uint16(0x300), c.I, "I unchanged")
+		}},
+		{"Dxyn DRW collision", 0xD121, func(c *chip8) {
+			c.I = 0x300
+			c.memory[0x300] = 0x80
+			c.V[1], c.V[2] = 2, 3
+			c.SetPixel(2, 3, true)
+		}, func(t *testing.T, c *chip8) {
+			assert.False(t, c.Pixel(2, 3))
+			assert.Equal(t, byte(1), c.V[0xF])
+		}},
+		{"Ex9E SKP pressed", 0xE49E, func(c *chip8) {
+			c.V[4] = 0xA
+			c.KeyDown(0xA)
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x204), c.PC)
+		}},
+		{"Ex9E SKP released", 0xE49E, func(c *chip8) {
+			c.V[4] = 0xA
+			c.KeyDown(0xB)
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x202), c.PC)
+		}},
+		{"ExA1 SKNP pressed", 0xE4A1, func(c *chip8) {
+			c.V[4] = 0xA
+			c.KeyDown(0xA)
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x202), c.PC)
+		}},
+		{"ExA1 SKNP released", 0xE4A1, func(c *chip8) {
+			c.V[4] = 0xA
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x204), c.PC)
+		}},
+		{"F000 LD I, long", 0xF000, func(c *chip8) {
+			c.memory[0x202], c.memory[0x203] = 0x0A, 0xBC
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x0ABC), c.I)
+			assert.Equal(t, uint16(0x204), c.PC)
+		}},
+		{"FN01 PLANE", 0xF201, nil, func(t *testing.T, c *chip8) {
+			assert.Equal(t, byte(0x2), c.planes)
+		}},
+		{"F002 AUDIO", 0xF002, func(c *chip8) {
+			c.I = 0x300
+			for i := 0; i < 16; i++ {
+				c.memory[0x300+i] = byte(i)
+			}
+		}, func(t *testing.T, c *chip8) {
+			assert.True(t, c.hasPattern)
+			assert.Equal(t, c.memory[0x300:0x310], c.audioPattern[:])
+		}},
+		{"Fx07 LD Vx, DT", 0xF507, func(c *chip8) {
+			c.SetDelayTimer(0x42)
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, byte(0x42), c.V[5])
+		}},
+		{"Fx0A LD Vx, K waiting", 0xF60A, nil, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x200), c.PC)
+		}},
+		{"Fx0A LD Vx, K pressed", 0xF60A, func(c *chip8) {
+			c.KeyDown(0xB)
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, byte(0xB), c.V[6])
+			assert.Equal(t, uint16(0x202), c.PC)
+		}},
+		{"Fx15 LD DT, Vx", 0xF715, func(c *chip8) {
+			c.V[7] = 9
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, byte(9), c.DelayTimer())
+		}},
+		{"Fx18 LD ST, Vx", 0xF718, func(c *chip8) {
+			c.V[7] = 4
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, byte(4), c.SoundTimer())
+		}},
+		{"Fx1E ADD I, Vx", 0xF81E, func(c *chip8) {
+			c.I, c.V[8], c.V[0xF] = 0x100, 0x20, 0x55
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0x120), c.I)
+			assert.Equal(t, byte(0x55), c.V[0xF], "VF untouched")
+		}},
+		{"Fx1E ADD I, Vx overflow", 0xF81E, func(c *chip8) {
+			c.Quirks.IndexOverflowSetsVF = true
+			c.I, c.V[8] = 0xFFF, 1
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(0), c.I, "wrapped")
+			assert.Equal(t, byte(1), c.V[0xF])
+		}},
+		{"Fx29 LD F, Vx", 0xF929, func(c *chip8) {
+			c.V[9] = 0xA
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(FontBase+0xA*FontHeight), c.I)
+		}},
+		{"Fx29 LD F, Vx high nibble", 0xF929, func(c *chip8) {
+			c.V[9] = 0x3A
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(FontBase+0xA*FontHeight), c.I)
+		}},
+		{"Fx30 LD HF, Vx", 0xF930, func(c *chip8) {
+			c.V[9] = 2
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, uint16(LargeFontBase+2*LargeFontHeight), c.I)
+		}},
+		{"Fx33 LD B, Vx", 0xF033, func(c *chip8) {
+			c.I, c.V[0] = 0x300, 137
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, []byte{1, 3, 7}, c.memory[0x300:0x303])
+			assert.Equal(t, uint16(0x300), c.I)
+		}},
+		{"Fx55 LD [I], Vx", 0xF255, func(c *chip8) {
+			c.I = 0x300
+			c.V[0], c.V[1], c.V[2], c.V[3] = 1, 2, 3, 4
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, []byte{1, 2, 3, 0}, c.memory[0x300:0x304])
+			assert.Equal(t, uint16(0x303), c.I)
+		}},
+		{"Fx65 LD Vx, [I]", 0xF265, func(c *chip8) {
+			c.I = 0x300
+			copy(c.memory[0x300:], []byte{9, 8, 7, 6})
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, [4]byte{9, 8, 7, 0}, [4]byte{c.V[0], c.V[1], c.V[2], c.V[3]})
+			assert.Equal(t, uint16(0x303), c.I)
+		}},
+		{"Fx75 LD R, Vx", 0xF275, func(c *chip8) {
+			c.V[0], c.V[1], c.V[2], c.V[3] = 1, 2, 3, 4
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, [8]byte{1, 2, 3}, c.rplFlags)
+		}},
+		{"Fx85 LD Vx, R", 0xF285, func(c *chip8) {
+			c.rplFlags = [8]byte{5, 6, 7, 8}
+		}, func(t *testing.T, c *chip8) {
+			assert.Equal(t, [4]byte{5, 6, 7, 0}, [4]byte{c.V[0], c.V[1], c.V[2], c.V[3]})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestChip8(DefaultMemorySize)
+			c.LoadBytes(0x200, []byte{byte(tt.op >> 8), byte(tt.op)})
+			if tt.setup != nil {
+				tt.setup(c)
+			}
+
+			assert.NoError(t, c.Step())
+			tt.check(t, c)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package interpreter
+
+import "fmt"
+
+// Profile names a CHIP-8 variant for NewChip8Profile.
+type Profile uint8
+
+const (
+	CosmacVIP       Profile = iota // The original COSMAC VIP interpreter: Chip8Quirks, 4KB
+	SuperChipModern                // SUPER-CHIP as modern interpreters run it: SuperChipQuirks, 4KB
+	SuperChipLegacy                // SUPER-CHIP 1.1 on the HP-48, waiting for vblank to draw, 4KB
+	XOChip                         // Octo's XO-CHIP: XOChipQuirks, 64KB
+)
+
+func (p Profile) String() string {
+	switch p {
+	case CosmacVIP:
+		return "COSMAC VIP"
+	case SuperChipModern:
+		return "SUPER-CHIP (modern)"
+	case SuperChipLegacy:
+		return "SUPER-CHIP (legacy)"
+	case XOChip:
+		return "XO-CHIP"
+	}
+	return fmt.Sprintf("Profile(%d)", uint8(p))
+}
+
+// quirks returns the quirk preset of p.
+func (p Profile) quirks() Quirks {
+	switch p {
+	case SuperChipModern:
+		return SuperChipQuirks
+	case SuperChipLegacy:
+		q := SuperChipQuirks
+		q.DisplayWait = true
+		return q
+	case XOChip:
+		return XOChipQuirks
+	}
+	return Chip8Quirks
+}
+
+// memorySize returns how much memory programs for p can address.
+func (p Profile) memorySize() int {
+	if p == XOChip {
+		return MaxMemorySize
+	}
+	return DefaultMemorySize
+}
+
+// NewChip8Profile returns an interpreter configured for p: its quirks and
+// memory size. Every profile starts in 64x32 low resolution with both fonts
+// loaded, as the SUPER-CHIP and XO-CHIP programs expect, and at
+// DefaultClockSpeed. An unknown profile is treated as CosmacVIP.
+func NewChip8Profile(p Profile) Machine {
+	return newChip8Profile(p)
+}
+
+func newChip8Profile(p Profile) *chip8 {
+	c := newChip8(p.memorySize())
+	c.Quirks = p.quirks()
+	return c
+}
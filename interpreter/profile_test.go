@@ -0,0 +1,53 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewChip8Profile(t *testing.T) {
+	legacy := SuperChipQuirks
+	legacy.DisplayWait = true
+	tests := []struct {
+		profile Profile
+		quirks  Quirks
+		memory  int
+	}{
+		{CosmacVIP, Chip8Quirks, 0x1000},
+		{SuperChipModern, SuperChipQuirks, 0x1000},
+		{SuperChipLegacy, legacy, 0x1000},
+		{XOChip, XOChipQuirks, 0x10000},
+		{Profile(42), Chip8Quirks, 0x1000},
+	}
+	for _, tt := range tests {
+		chip8 := NewChip8Profile(tt.profile).(*chip8)
+
+		assert.Equal(t, tt.quirks, chip8.Quirks, tt.profile.String())
+		assert.Len(t, chip8.memory, tt.memory, tt.profile.String())
+		w, h := chip8.Dimensions()
+		assert.Equal(t, [2]int{64, 32}, [2]int{w, h}, tt.profile.String())
+		assert.Equal(t, uint16(0x200), chip8.PC, tt.profile.String())
+	}
+}
+
+func TestProfileString(t *testing.T) {
+	assert.Equal(t, "COSMAC VIP", CosmacVIP.String())
+	assert.Equal(t, "XO-CHIP", XOChip.String())
+	assert.Equal(t, "Profile(42)", Profile(42).String())
+}
+
+func TestResetReloadsFontsPerProfile(t *testing.T) {
+	for _, p := range []Profile{SuperChipModern, SuperChipLegacy, XOChip} {
+		chip8 := newChip8Profile(p)
+		for i := 0; i < 0x200; i++ {
+			chip8.memory[i] = 0xFF
+		}
+
+		chip8.Reset()
+
+		assert.Equal(t, FontSet, chip8.memory[0x50:0x50+len(FontSet)], p.String())
+		assert.Equal(t, LargeFontSet, chip8.memory[LargeFontBase:LargeFontBase+len(LargeFontSet)], p.String())
+		assert.Len(t, chip8.memory, p.memorySize(), p.String())
+	}
+}
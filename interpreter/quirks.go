@@ -0,0 +1,70 @@
+package interpreter
+
+// Quirks selects between behaviours that CHIP-8 interpreters disagree on.
+// The zero value keeps this interpreter's original behaviour, except that
+// sprites running off the screen are clipped unless WrapSprites is set and
+// Fx55/Fx65 advance I by x+1 as on the COSMAC VIP.
+type Quirks struct {
+	ShiftUsesVy         bool           // 8xy6/8xyE shift Vy and store the result in Vx
+	LoadStoreIncrement  IndexIncrement // How far Fx55/Fx65 advance I
+	JumpUsesVx          bool           // Bxnn jumps to xnn + Vx instead of nnn + V0
+	JumpOverflow        JumpOverflow   // What Bnnn does with a target past the end of memory
+	DisplayWait         bool           // Dxyn waits for vblank, so at most one draw per frame
+	WrapSprites         bool           // Dxyn wraps pixels past the edges instead of clipping them
+	IndexOverflowSetsVF bool           // Fx1E sets VF when I+Vx passes 0x0FFF (Amiga interpreter)
+	LogicOpsResetVF     bool           // 8xy1/8xy2/8xy3 clear VF, as on the COSMAC VIP
+	FlagBeforeResult    bool           // 8xy4-8xyE write VF before Vx, so the result wins when x is VF
+}
+
+// IndexIncrement is how far Fx55/Fx65 advance I after storing or loading
+// V0..Vx.
+type IndexIncrement uint8
+
+const (
+	IndexIncrementXPlus1 IndexIncrement = iota // I += x + 1, past the last register (COSMAC VIP)
+	IndexIncrementNone                         // I is left unchanged (SUPER-CHIP 1.1)
+	IndexIncrementX                            // I += x, onto the last register (SUPER-CHIP 1.0)
+)
+
+// advance returns how much to add to I after transferring V0..Vx.
+func (inc IndexIncrement) advance(x uint8) uint16 {
+	switch inc {
+	case IndexIncrementNone:
+		return 0
+	case IndexIncrementX:
+		return uint16(x)
+	}
+	return uint16(x) + 1
+}
+
+// JumpOverflow is what Bnnn does when nnn plus the register overflows the
+// memory, e.g. B0FF with V0 = 0xFF on a 4KB machine.
+type JumpOverflow uint8
+
+const (
+	JumpWrap  JumpOverflow = iota // Wrap around to the start of memory, as 12-bit address hardware does
+	JumpClamp                     // Stop at the last instruction in memory
+	JumpError                     // Fail with an AddressError
+)
+
+var (
+	// Chip8Quirks matches the original COSMAC VIP interpreter.
+	Chip8Quirks = Quirks{
+		ShiftUsesVy:        true,
+		LoadStoreIncrement: IndexIncrementXPlus1,
+		DisplayWait:        true,
+		LogicOpsResetVF:    true,
+	}
+	// SuperChipQuirks matches SUPER-CHIP as modern interpreters run it,
+	// without the HP-48's wait for vblank before drawing.
+	SuperChipQuirks = Quirks{
+		LoadStoreIncrement: IndexIncrementNone,
+		JumpUsesVx:         true,
+	}
+	// XOChipQuirks matches Octo's XO-CHIP defaults.
+	XOChipQuirks = Quirks{
+		ShiftUsesVy:        true,
+		LoadStoreIncrement: IndexIncrementXPlus1,
+		WrapSprites:        true,
+	}
+)
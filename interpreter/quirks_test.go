@@ -0,0 +1,332 @@
+package interpreter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShiftUsesVy(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x82, 0x36, 0x84, 0x5E} // SHR V2, V3; SHL V4, V5
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.Quirks.ShiftUsesVy = true
+	chip8.V[2] = 0xFF
+	chip8.V[3] = 0x08
+	chip8.V[4] = 0xFF
+	chip8.V[5] = 0x81
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint8(0x04), chip8.V[2])
+	assert.Equal(t, uint8(0x00), chip8.V[0xF])
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint8(0x02), chip8.V[4])
+	assert.Equal(t, uint8(0x01), chip8.V[0xF])
+}
+
+func TestLoadStoreIncrement(t *testing.T) {
+	tests := []struct {
+		inc  IndexIncrement
+		want uint16
+	}{
+		{IndexIncrementXPlus1, 0x303},
+		{IndexIncrementX, 0x302},
+		{IndexIncrementNone, 0x300},
+	}
+	for _, tt := range tests {
+		for _, op := range []byte{0x55, 0x65} { // LD [I], V2 and LD V2, [I]
+			chip8 := newTestChip8(DefaultMemorySize)
+			chip8.LoadBytes(0x200, []byte{0xF2, op})
+			chip8.LoadBytes(0x300, []byte{0x11, 0x22, 0x33})
+			chip8.Quirks.LoadStoreIncrement = tt.inc
+			chip8.I = 0x300
+
+			assert.NoError(t, chip8.Step())
+			assert.Equal(t, tt.want, chip8.I, "increment %d, Fx%02X", tt.inc, op)
+		}
+	}
+}
+
+func TestLoadStoreIncrementDefault(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0xF1, 0x65}) // LD V1, [I]
+	chip8.I = 0x300
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x302), chip8.I)
+}
+
+func TestJumpUsesVx(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xB3, 0x00} // JP V3, 0x300
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.Quirks.JumpUsesVx = true
+	chip8.V[0] = 0x10
+	chip8.V[3] = 0x20
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x320), chip8.PC)
+}
+
+func TestJumpOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		overflow JumpOverflow
+		pc       uint16
+	}{
+		{JumpWrap, 0x00EE},
+		{JumpClamp, 0x0FFE},
+	} {
+		chip8 := newTestChip8(DefaultMemorySize)
+		chip8.LoadBytes(0x200, []byte{0xBF, 0xF0}) // JP V0, 0xFF0
+		chip8.Quirks.JumpOverflow = tt.overflow
+		chip8.V[0] = 0xFE
+
+		assert.NoError(t, chip8.Step())
+		assert.Equal(t, tt.pc, chip8.PC, "overflow %d", tt.overflow)
+	}
+}
+
+func TestJumpOverflowLastByte(t *testing.T) {
+	for _, tt := range []struct {
+		overflow JumpOverflow
+		pc       uint16
+	}{
+		{JumpWrap, 0x0000},
+		{JumpClamp, 0x0FFE},
+	} {
+		chip8 := newTestChip8(DefaultMemorySize)
+		chip8.LoadBytes(0x200, []byte{0xBF, 0x00}) // JP V0, 0xF00
+		chip8.Quirks.JumpOverflow = tt.overflow
+		chip8.V[0] = 0xFF
+
+		assert.NoError(t, chip8.Step())
+		assert.Equal(t, tt.pc, chip8.PC, "overflow %d", tt.overflow)
+		assert.NotPanics(t, func() { chip8.Step() })
+	}
+}
+
+func TestJumpOverflowError(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0xBF, 0xF0}) // JP V0, 0xFF0
+	chip8.Quirks.JumpOverflow = JumpError
+	chip8.V[0] = 0xFE
+
+	err := chip8.Step()
+
+	var aerr *AddressError
+	if assert.ErrorAs(t, err, &aerr) {
+		assert.Equal(t, 0x10EE, aerr.Addr)
+		assert.Equal(t, uint16(0x200), aerr.PC)
+	}
+	assert.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestJumpOverflowWithVx(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0xB3, 0xFF}) // JP V3, 0x3FF
+	chip8.Quirks.JumpUsesVx = true
+	chip8.Quirks.JumpOverflow = JumpClamp
+	chip8.V[3] = 0xFF
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x4FE), chip8.PC, "in range, not clamped")
+
+	chip8.PC = 0x200
+	chip8.LoadBytes(0x200, []byte{0xBF, 0xFF}) // JP VF, 0xFFF
+	chip8.V[0xF] = 0x01
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x0FFE), chip8.PC)
+}
+
+func TestLogicOpsResetVF(t *testing.T) {
+	for _, op := range []byte{0x1, 0x2, 0x3} {
+		for _, quirk := range []bool{true, false} {
+			chip8 := newTestChip8(DefaultMemorySize)
+			chip8.LoadBytes(0x200, []byte{0x81, 0x20 | op}) // OR/AND/XOR V1, V2
+			chip8.Quirks.LogicOpsResetVF = quirk
+			chip8.V[1], chip8.V[2] = 0x0C, 0x0A
+			chip8.V[0xF] = 0x55
+
+			assert.NoError(t, chip8.Step())
+
+			want := byte(0x55)
+			if quirk {
+				want = 0
+			}
+			assert.Equal(t, want, chip8.V[0xF], "8xy%X with quirk %v", op, quirk)
+			assert.Equal(t, [3]byte{0x0E, 0x08, 0x06}[op-1], chip8.V[1])
+		}
+	}
+	assert.True(t, Chip8Quirks.LogicOpsResetVF)
+}
+
+func TestFlagBeforeResult(t *testing.T) {
+	tests := []struct {
+		op           byte // Low nibble of 8F1n
+		vf, v1       byte
+		result, flag byte
+	}{
+		{0x4, 0xF0, 0x20, 0x10, 1}, // ADD VF, V1
+		{0x5, 0x30, 0x10, 0x20, 1}, // SUB VF, V1
+		{0x6, 0x06, 0x00, 0x03, 0}, // SHR VF
+		{0x7, 0x10, 0x30, 0x20, 1}, // SUBN VF, V1
+		{0xE, 0x81, 0x00, 0x02, 1}, // SHL VF
+	}
+	for _, tt := range tests {
+		for _, quirk := range []bool{false, true} {
+			chip8 := newTestChip8(DefaultMemorySize)
+			chip8.LoadBytes(0x200, []byte{0x8F, 0x10 | tt.op})
+			chip8.Quirks.FlagBeforeResult = quirk
+			chip8.V[0xF], chip8.V[1] = tt.vf, tt.v1
+
+			assert.NoError(t, chip8.Step())
+
+			want := tt.flag
+			if quirk {
+				want = tt.result
+			}
+			assert.Equal(t, want, chip8.V[0xF], "8F1%X with quirk %v", tt.op, quirk)
+		}
+	}
+}
+
+func TestDisplayWait(t *testing.T) {
+	drawsPerFrame := func(displayWait bool) []int {
+		chip8 := newTestChip8(DefaultMemorySize)
+		chip8.SetClockSpeed(600) // 10 instructions per frame
+		// DRW V0, V0, 1; DRW V0, V0, 1; JP 0x200
+		chip8.LoadBytes(0x200, []byte{0xD0, 0x01, 0xD0, 0x01, 0x12, 0x00})
+		chip8.Quirks.DisplayWait = displayWait
+		draws := 0
+		chip8.SetTraceFunc(func(pc uint16, op uint16, regs [16]byte) {
+			if op&0xF000 == 0xD000 {
+				draws++
+			}
+		})
+
+		var counts []int
+		for i := 0; i < 3; i++ {
+			draws = 0
+			assert.NoError(t, chip8.RunFrame())
+			counts = append(counts, draws)
+		}
+		return counts
+	}
+
+	assert.Equal(t, []int{1, 1, 1}, drawsPerFrame(true))
+	assert.Equal(t, []int{7, 7, 6}, drawsPerFrame(false))
+}
+
+func TestSuggestQuirks(t *testing.T) {
+	tests := []struct {
+		hash   string
+		quirks Quirks
+		ok     bool
+	}{
+		{"00bb7001de52b562d98357d33375406c6d0308466d7d95c2580537d2451a75cd", SuperChipQuirks, true},
+		{"29950384966D7ECAC743FD189FF5123D1617CE379F11B734BBAC22667C4ACA23", Chip8Quirks, true},
+		{"69970ad2", Chip8Quirks, true},
+		{"0000000000000000000000000000000000000000000000000000000000000000", Quirks{}, false},
+		{"", Quirks{}, false},
+	}
+	for _, tt := range tests {
+		quirks, ok := SuggestQuirks(tt.hash)
+		assert.Equal(t, tt.ok, ok, tt.hash)
+		assert.Equal(t, tt.quirks, quirks, tt.hash)
+	}
+}
+
+func TestSuggestQuirksFromRomInfo(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	f, err := os.Open("../roms/space_invaders.ch8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	info, err := chip8.LoadRomInfo(f)
+	assert.NoError(t, err)
+	quirks, ok := SuggestQuirks(info.Hash())
+	assert.True(t, ok)
+	assert.Equal(t, SuperChipQuirks, quirks)
+}
+
+func TestWrapSprites(t *testing.T) {
+	tests := []struct {
+		wrap bool
+		top  bool
+	}{
+		{false, false},
+		{true, true},
+	}
+	for _, tt := range tests {
+		chip8 := newTestChip8(DefaultMemorySize)
+		chip8.Quirks.WrapSprites = tt.wrap
+		// LD I, 0x300; DRW V0, V1, 4 with rows 30-33 straddling the bottom
+		testBytes := []byte{0xA3, 0x00, 0xD0, 0x14}
+		chip8.LoadBytes(0x200, testBytes)
+		chip8.LoadBytes(0x300, []byte{0x80, 0x80, 0x80, 0x80})
+		chip8.V[0] = 10
+		chip8.V[1] = 30
+
+		assert.NoError(t, chip8.RunCycles(2))
+		assert.True(t, chip8.Pixel(10, 30))
+		assert.True(t, chip8.Pixel(10, 31))
+		assert.Equal(t, tt.top, chip8.Pixel(10, 0), "wrap %v", tt.wrap)
+		assert.Equal(t, tt.top, chip8.Pixel(10, 1), "wrap %v", tt.wrap)
+	}
+}
+
+func TestWrapSpritesOriginAlwaysWraps(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD I, 0x300; DRW V0, V1, 1 at (74, 40), which is (10, 8) wrapped
+	testBytes := []byte{0xA3, 0x00, 0xD0, 0x11}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0x80})
+	chip8.V[0] = 74
+	chip8.V[1] = 40
+
+	assert.NoError(t, chip8.RunCycles(2))
+	assert.True(t, chip8.Pixel(10, 8))
+}
+
+func TestIndexOverflowSetsVF(t *testing.T) {
+	tests := []struct {
+		quirk bool
+		i     uint16
+		wantI uint16
+		vf    byte
+	}{
+		{false, 0x0FE0, 0x0FF0, 0x7},
+		{false, 0x0FF8, 0x0008, 0x7},
+		{true, 0x0FE0, 0x0FF0, 0x0},
+		{true, 0x0FEF, 0x0FFF, 0x0},
+		{true, 0x0FF8, 0x0008, 0x1},
+	}
+	for _, tt := range tests {
+		chip8 := newTestChip8(DefaultMemorySize)
+		chip8.Quirks.IndexOverflowSetsVF = tt.quirk
+		chip8.LoadBytes(0x200, []byte{0xF1, 0x1E}) // ADD I, V1
+		chip8.I = tt.i
+		chip8.V[1] = 0x10
+		chip8.V[0xF] = 0x7
+
+		assert.NoError(t, chip8.Step())
+		assert.Equal(t, tt.wantI, chip8.I, "quirk %v, I 0x%04X", tt.quirk, tt.i)
+		assert.Equal(t, tt.vf, chip8.V[0xF], "quirk %v, I 0x%04X", tt.quirk, tt.i)
+	}
+}
+
+func TestIndexOverflowLargeMemory(t *testing.T) {
+	chip8 := newTestChip8(MaxMemorySize)
+	chip8.Quirks.IndexOverflowSetsVF = true
+	chip8.LoadBytes(0x200, []byte{0xF1, 0x1E}) // ADD I, V1
+	chip8.I = 0x0FF8
+	chip8.V[1] = 0x10
+
+	assert.NoError(t, chip8.Step())
+	assert.Equal(t, uint16(0x1008), chip8.I)
+	assert.Equal(t, byte(1), chip8.V[0xF])
+}
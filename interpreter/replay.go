@@ -0,0 +1,51 @@
+package interpreter
+
+// InputEvent is a key press or release recorded at the instruction count it
+// happened at, as returned by Cycles. Its fields are exported so recordings
+// can be saved with encoding/json or encoding/gob.
+type InputEvent struct {
+	Cycle uint64 `json:"cycle"`
+	Key   uint8  `json:"key"`
+	Down  bool   `json:"down"`
+}
+
+// StartRecording discards any previous recording and starts recording every
+// KeyDown and KeyUp. Seed the machine as well for runs that use RND.
+func (c *chip8) StartRecording() {
+	c.recording = true
+	c.events = nil
+}
+
+// StopRecording stops recording and returns the recorded events.
+func (c *chip8) StopRecording() []InputEvent {
+	events := c.events
+	c.recording = false
+	c.events = nil
+	return events
+}
+
+// Replay presses and releases keys as recorded in events, each one just
+// before the instruction at its cycle executes. Replaying against a fresh
+// machine with the same ROM and seed reproduces the recorded run.
+func (c *chip8) Replay(events []InputEvent) {
+	c.replay = append([]InputEvent(nil), events...)
+}
+
+func (c *chip8) record(key uint8, down bool) {
+	if c.recording {
+		c.events = append(c.events, InputEvent{Cycle: c.cycles, Key: key, Down: down})
+	}
+}
+
+// replayInput applies the replayed events that are due.
+func (c *chip8) replayInput() {
+	for len(c.replay) > 0 && c.replay[0].Cycle <= c.cycles {
+		ev := c.replay[0]
+		c.replay = c.replay[1:]
+		if ev.Down {
+			c.KeyDown(ev.Key)
+		} else {
+			c.KeyUp(ev.Key)
+		}
+	}
+}
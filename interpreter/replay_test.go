@@ -0,0 +1,73 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// replayROM waits for a key, draws a random byte at the key's coordinates
+// and loops.
+var replayROM = []byte{
+	0xF0, 0x0A, // LD V0, K
+	0xC1, 0xFF, // RND V1, 0xFF
+	0xA3, 0x00, // LD I, 0x300
+	0xF1, 0x55, // LD [I], V1
+	0xD0, 0x01, // DRW V0, V0, 1
+	0x12, 0x00, // JP 0x200
+}
+
+func TestRecordReplay(t *testing.T) {
+	recorded := newTestChip8(DefaultMemorySize)
+	recorded.Seed(42)
+	recorded.LoadBytes(0x200, replayROM)
+
+	recorded.StartRecording()
+	assert.NoError(t, recorded.RunCycles(10))
+	recorded.KeyDown(0x3)
+	assert.NoError(t, recorded.RunCycles(3))
+	recorded.KeyUp(0x3)
+	assert.NoError(t, recorded.RunCycles(20))
+	recorded.KeyDown(0xA)
+	assert.NoError(t, recorded.RunCycles(7))
+	recorded.KeyUp(0xA)
+	assert.NoError(t, recorded.RunCycles(10))
+	events := recorded.StopRecording()
+
+	assert.Equal(t, []InputEvent{
+		{Cycle: 10, Key: 0x3, Down: true},
+		{Cycle: 13, Key: 0x3, Down: false},
+		{Cycle: 33, Key: 0xA, Down: true},
+		{Cycle: 40, Key: 0xA, Down: false},
+	}, events)
+
+	// Save and load the recording as a frontend would
+	data, err := json.Marshal(events)
+	assert.NoError(t, err)
+	var loaded []InputEvent
+	assert.NoError(t, json.Unmarshal(data, &loaded))
+
+	replayed := newTestChip8(DefaultMemorySize)
+	replayed.Seed(42)
+	replayed.LoadBytes(0x200, replayROM)
+	replayed.Replay(loaded)
+	assert.NoError(t, replayed.RunCycles(int(recorded.Cycles())))
+
+	assert.Equal(t, recorded.Cycles(), replayed.Cycles())
+	assert.Equal(t, recorded.V, replayed.V)
+	assert.Equal(t, recorded.I, replayed.I)
+	assert.Equal(t, recorded.PC, replayed.PC)
+	assert.Equal(t, recorded.memory, replayed.memory)
+	assert.Equal(t, recorded.Frame(), replayed.Frame())
+}
+
+func TestStopRecordingWithoutEvents(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.KeyDown(0x1)
+	chip8.StartRecording()
+	assert.Empty(t, chip8.StopRecording())
+
+	chip8.KeyDown(0x2)
+	assert.Empty(t, chip8.events)
+}
@@ -0,0 +1,100 @@
+package interpreter
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// RomInfo describes a ROM read by LoadRomInfo.
+type RomInfo struct {
+	Size     int      // Bytes read from the reader
+	Loaded   int      // Bytes copied into memory
+	TooLarge bool     // ROM did not fit between the entry point and the end of memory
+	Odd      bool     // Size is odd, so the last instruction is padded with 0x00
+	CRC32    uint32   // IEEE CRC-32 of the whole ROM
+	SHA256   [32]byte // SHA-256 of the whole ROM
+}
+
+// LoadRomInfo reads r to EOF, loads it at the entry point and reports its
+// size and checksums. A ROM that does not fit is not loaded; its info is still
+// returned along with ErrRomTooLarge.
+func (c *chip8) LoadRomInfo(r io.Reader) (RomInfo, error) {
+	rom, err := io.ReadAll(r)
+	if err != nil {
+		return RomInfo{}, err
+	}
+	info := RomInfo{
+		Size:   len(rom),
+		Odd:    len(rom)%2 != 0,
+		CRC32:  crc32.ChecksumIEEE(rom),
+		SHA256: sha256.Sum256(rom),
+	}
+	info.Loaded, err = c.LoadRomBytes(rom)
+	if err == ErrRomTooLarge {
+		info.TooLarge = true
+	}
+	return info, err
+}
+
+// LoadRomSwapped loads a ROM dumped with the bytes of every 16-bit word
+// swapped, as some tools produce, swapping them back into the big-endian
+// order Fetch expects. A trailing odd byte is loaded as is. Normal ROMs
+// should be loaded with LoadRom.
+func (c *chip8) LoadRomSwapped(r io.Reader) (int, error) {
+	rom, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i+1 < len(rom); i += 2 {
+		rom[i], rom[i+1] = rom[i+1], rom[i]
+	}
+	return c.LoadRomBytes(rom)
+}
+
+// LoadRomFromFile loads the ROM at path and closes the file again.
+func (c *chip8) LoadRomFromFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err // *fs.PathError already names the file
+	}
+	defer f.Close()
+
+	n, err := c.LoadRom(f)
+	if err != nil {
+		return n, fmt.Errorf("loading %s: %w", path, err)
+	}
+	return n, nil
+}
+
+// segment is a range of memory filled by LoadRomBytes or LoadAt.
+type segment struct {
+	start, end int
+}
+
+// LoadAt copies data into memory at addr, for overlays and data blobs that
+// live apart from the ROM at 0x200. Data that would run past the end of
+// memory is not loaded and ErrLoadOutOfRange is returned. Loading over the
+// interpreter area below 0x200, the ROM or an earlier LoadAt is allowed but
+// logged as a warning.
+func (c *chip8) LoadAt(addr uint16, data []byte) (int, error) {
+	s := segment{int(addr), int(addr) + len(data)}
+	if s.end > len(c.memory) {
+		return 0, fmt.Errorf("%w: %d bytes at 0x%04X", ErrLoadOutOfRange, len(data), addr)
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	if s.start < programStart {
+		c.logger.Printf("Warning: load at 0x%04X overwrites the interpreter area below 0x200", addr)
+	}
+	for _, o := range c.segments {
+		if s.start < o.end && o.start < s.end {
+			c.logger.Printf("Warning: load at 0x%04X-0x%04X overlaps 0x%04X-0x%04X", s.start, s.end-1, o.start, o.end-1)
+		}
+	}
+	c.segments = append(c.segments, s)
+	return copy(c.memory[addr:], data), nil
+}
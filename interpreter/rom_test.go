@@ -0,0 +1,157 @@
+package interpreter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash/crc32"
+	"io/fs"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRomInfo(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	rom := []byte{0x62, 0x69, 0x12, 0x00}
+
+	info, err := chip8.LoadRomInfo(bytes.NewReader(rom))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, info.Size)
+	assert.Equal(t, 4, info.Loaded)
+	assert.False(t, info.TooLarge)
+	assert.False(t, info.Odd)
+	assert.Equal(t, crc32.ChecksumIEEE(rom), info.CRC32)
+	assert.Equal(t, sha256.Sum256(rom), info.SHA256)
+	assert.Equal(t, rom, chip8.memory[0x200:0x204])
+}
+
+func TestLoadRomInfoEmpty(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	info, err := chip8.LoadRomInfo(bytes.NewReader(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, info.Size)
+	assert.Equal(t, 0, info.Loaded)
+	assert.False(t, info.TooLarge)
+}
+
+func TestLoadRomInfoOddLength(t *testing.T) {
+	var buf bytes.Buffer
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetLogger(log.New(&buf, "", 0))
+	chip8.memory[0x205] = 0xFF // Left over from an earlier ROM
+	// LD V0, 1; LD V1, 2; then a lone 0x63
+	rom := []byte{0x60, 0x01, 0x61, 0x02, 0x63}
+
+	info, err := chip8.LoadRomInfo(bytes.NewReader(rom))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, info.Size)
+	assert.Equal(t, 5, info.Loaded)
+	assert.True(t, info.Odd)
+	assert.Contains(t, buf.String(), "odd length")
+	assert.Equal(t, byte(0x00), chip8.memory[0x205])
+
+	// The last fetch sees LD V3, 0x00 instead of reading the stale byte
+	assert.NoError(t, chip8.RunCycles(3))
+	assert.Equal(t, uint16(0x206), chip8.PC)
+	assert.Equal(t, byte(0x00), chip8.V[3])
+}
+
+func TestLoadRomInfoTooLarge(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	rom := bytes.Repeat([]byte{0xAA}, 0x1000-0x200+1)
+
+	info, err := chip8.LoadRomInfo(bytes.NewReader(rom))
+
+	assert.ErrorIs(t, err, ErrRomTooLarge)
+	assert.Equal(t, len(rom), info.Size)
+	assert.Equal(t, 0, info.Loaded)
+	assert.True(t, info.TooLarge)
+	assert.Equal(t, byte(0x00), chip8.memory[0x200])
+}
+
+func TestLoadRomSwapped(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	swapped := []byte{0x69, 0x62, 0x06, 0x12, 0xAB} // LD V2, 0x69; JP 0x206 and a stray byte
+
+	n, err := chip8.LoadRomSwapped(bytes.NewReader(swapped))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	for _, want := range []uint16{0x6269, 0x1206} {
+		op, err := chip8.Fetch()
+		assert.NoError(t, err)
+		assert.Equal(t, want, op)
+	}
+	assert.Equal(t, byte(0xAB), chip8.memory[0x204])
+}
+
+func TestLoadRomFromFile(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	rom, err := os.ReadFile("../roms/pong.ch8")
+	assert.NoError(t, err)
+
+	n, err := chip8.LoadRomFromFile("../roms/pong.ch8")
+
+	assert.NoError(t, err)
+	assert.Equal(t, len(rom), n)
+	assert.Equal(t, rom, chip8.memory[0x200:0x200+n])
+}
+
+func TestLoadRomFromMissingFile(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	_, err := chip8.LoadRomFromFile("../roms/missing.ch8")
+
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Contains(t, err.Error(), "../roms/missing.ch8")
+}
+
+func TestLoadAt(t *testing.T) {
+	var buf bytes.Buffer
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetLogger(log.New(&buf, "", 0))
+	rom := []byte{0xA6, 0x00, 0xD0, 0x14} // LD I, 0x600; DRW V0, V1, 4
+	data := []byte{0xF0, 0x90, 0x90, 0xF0}
+
+	_, err := chip8.LoadRomBytes(rom)
+	assert.NoError(t, err)
+	n, err := chip8.LoadAt(0x600, data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Equal(t, rom, chip8.memory[0x200:0x204])
+	assert.Equal(t, data, chip8.memory[0x600:0x604])
+	assert.Zero(t, chip8.memory[0x604])
+	assert.Empty(t, buf.String(), "no overlap")
+}
+
+func TestLoadAtOutOfRange(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+
+	n, err := chip8.LoadAt(0xFFE, []byte{1, 2, 3})
+
+	assert.ErrorIs(t, err, ErrLoadOutOfRange)
+	assert.Zero(t, n)
+	assert.Equal(t, []byte{0, 0}, chip8.memory[0xFFE:], "nothing loaded")
+}
+
+func TestLoadAtOverlapWarning(t *testing.T) {
+	var buf bytes.Buffer
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetLogger(log.New(&buf, "", 0))
+	_, err := chip8.LoadRomBytes([]byte{0x12, 0x00, 0x00, 0x00})
+	assert.NoError(t, err)
+
+	n, err := chip8.LoadAt(0x202, []byte{0xAA, 0xBB, 0xCC})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []byte{0x12, 0x00, 0xAA, 0xBB, 0xCC}, chip8.memory[0x200:0x205])
+	assert.Contains(t, buf.String(), "load at 0x0202-0x0204 overlaps 0x0200-0x0203")
+}
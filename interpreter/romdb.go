@@ -0,0 +1,37 @@
+package interpreter
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// knownRoms lists ROMs whose quirks are known, identified by the hex
+// SHA-256 and CRC-32 of the whole file.
+var knownRoms = []struct {
+	name   string
+	sha256 string
+	crc32  string
+	quirks Quirks
+}{
+	{"Pong (1 player)", "29950384966d7ecac743fd189ff5123d1617ce379f11b734bbac22667c4aca23", "841fde23", Chip8Quirks},
+	{"Pong (alt)", "380d62da4bd05464dd3a73112cdfbf1ab9f2c78f3984103f6f6ccc0c5c76562f", "69970ad2", Chip8Quirks},
+	{"Space Invaders [David Winter]", "00bb7001de52b562d98357d33375406c6d0308466d7d95c2580537d2451a75cd", "6ff0a017", SuperChipQuirks},
+}
+
+// SuggestQuirks looks up the quirk preset a well-known ROM needs. romHash is
+// the hex SHA-256 (see RomInfo.Hash) or hex CRC-32 of the ROM, in either
+// case. It returns false for ROMs it does not know.
+func SuggestQuirks(romHash string) (Quirks, bool) {
+	h := strings.ToLower(strings.TrimSpace(romHash))
+	for _, rom := range knownRoms {
+		if h == rom.sha256 || h == rom.crc32 {
+			return rom.quirks, true
+		}
+	}
+	return Quirks{}, false
+}
+
+// Hash returns the hex SHA-256 of the ROM, as SuggestQuirks expects.
+func (info RomInfo) Hash() string {
+	return hex.EncodeToString(info.SHA256[:])
+}
@@ -0,0 +1,59 @@
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// ScreenshotScale is how many image pixels wide and high each display pixel
+// is in ScreenshotPNG unless SetScale says otherwise.
+const ScreenshotScale = 8
+
+// ScreenshotPNG writes the framebuffer at the current resolution to w as a
+// black and white PNG, lit pixels white, each scaled up ScreenshotScale
+// times, or by the SetScale size if one is set.
+func (c *chip8) ScreenshotPNG(w io.Writer) error {
+	frame := c.Framebuffer()
+	width, height := c.width(), c.height()
+	scale := ScreenshotScale
+	if c.scale != 0 {
+		scale = c.scale
+	}
+	img := image.NewPaletted(
+		image.Rect(0, 0, width*scale, height*scale),
+		color.Palette{color.Black, color.White},
+	)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if frame[y/scale][x/scale] {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// ScreenshotPBM writes the framebuffer at the current resolution to w as an
+// unscaled plain PBM (P1), where 1 is a lit pixel.
+func (c *chip8) ScreenshotPBM(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "P1\n%d %d\n", c.width(), c.height())
+	for _, row := range c.Framebuffer() {
+		for x, on := range row {
+			if x > 0 {
+				bw.WriteByte(' ')
+			}
+			if on {
+				bw.WriteByte('1')
+			} else {
+				bw.WriteByte('0')
+			}
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
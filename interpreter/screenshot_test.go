@@ -0,0 +1,77 @@
+package interpreter
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreenshotPNG(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD I, 0x300; DRW V0, V0, 2
+	testBytes := []byte{0xA3, 0x00, 0xD0, 0x02}
+	chip8.LoadBytes(0x200, testBytes)
+	chip8.LoadBytes(0x300, []byte{0x80, 0x40})
+	assert.NoError(t, chip8.RunCycles(2))
+
+	var buf bytes.Buffer
+	assert.NoError(t, chip8.ScreenshotPNG(&buf))
+	img, err := png.Decode(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	s := ScreenshotScale
+	assert.Equal(t, LowResWidth*s, img.Bounds().Dx())
+	assert.Equal(t, LowResHeight*s, img.Bounds().Dy())
+	white := color.GrayModel.Convert(color.White)
+	black := color.GrayModel.Convert(color.Black)
+	gray := func(x, y int) color.Color { return color.GrayModel.Convert(img.At(x, y)) }
+	assert.Equal(t, white, gray(0, 0))
+	assert.Equal(t, white, gray(s-1, s-1))
+	assert.Equal(t, black, gray(s, 0))
+	assert.Equal(t, black, gray(0, s))
+	assert.Equal(t, white, gray(s, s))
+	assert.Equal(t, black, gray(LowResWidth*s-1, LowResHeight*s-1))
+}
+
+func TestScreenshotPNGScale(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetPixel(1, 0, true)
+	chip8.SetScale(4)
+
+	var buf bytes.Buffer
+	assert.NoError(t, chip8.ScreenshotPNG(&buf))
+	img, err := png.Decode(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 256, img.Bounds().Dx())
+	assert.Equal(t, 128, img.Bounds().Dy())
+	gray := func(x, y int) color.Color { return color.GrayModel.Convert(img.At(x, y)) }
+	assert.Equal(t, color.GrayModel.Convert(color.Black), gray(3, 0))
+	assert.Equal(t, color.GrayModel.Convert(color.White), gray(4, 3))
+	assert.Equal(t, color.GrayModel.Convert(color.Black), gray(8, 0))
+}
+
+func TestScreenshotPBM(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetPixel(1, 0, true)
+	chip8.SetPixel(63, 31, true)
+
+	var buf bytes.Buffer
+	assert.NoError(t, chip8.ScreenshotPBM(&buf))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2+LowResHeight) {
+		assert.Equal(t, "P1", lines[0])
+		assert.Equal(t, "64 32", lines[1])
+		assert.True(t, strings.HasPrefix(lines[2], "0 1 0 "))
+		assert.True(t, strings.HasSuffix(lines[len(lines)-1], " 0 1"))
+	}
+}
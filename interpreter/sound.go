@@ -0,0 +1,100 @@
+package interpreter
+
+// Sound is implemented by frontends that can play the CHIP-8 beep. Start is
+// called when the sound timer becomes nonzero and Stop when it runs out.
+type Sound interface {
+	Start()
+	Stop()
+}
+
+// PatternSound is implemented by Sound frontends that can play the XO-CHIP
+// audio pattern buffer instead of a plain square wave. SetPattern is called
+// whenever the ROM loads a new pattern with F002.
+type PatternSound interface {
+	Sound
+	SetPattern(pattern [16]byte)
+}
+
+type nopSound struct{}
+
+func (nopSound) Start() {}
+func (nopSound) Stop()  {}
+
+// SetSound installs the frontend that plays the beep. A nil Sound silences
+// the interpreter again.
+func (c *chip8) SetSound(s Sound) {
+	if s == nil {
+		s = nopSound{}
+	}
+	c.sound = s
+}
+
+// Audio sample generation for SetAudioCallback.
+const (
+	DefaultSampleRate = 44100
+	ToneFrequency     = 440  // Square wave pitch without an XO-CHIP pattern
+	PatternRate       = 4000 // XO-CHIP pattern bits played per second
+	audioVolume       = 0.25
+)
+
+// AudioFunc receives each frame's worth of samples, sampleRate/60 of them,
+// in the range -1 to 1. buf is reused for the next frame.
+type AudioFunc func(buf []float32, sampleRate int)
+
+// SetAudioCallback installs f to be handed raw samples after every frame of
+// RunFrame: a square wave, or the XO-CHIP pattern once F002 has loaded one,
+// while the sound timer is nonzero, and silence otherwise. A nil f turns
+// sample generation off again, which is the default.
+func (c *chip8) SetAudioCallback(f AudioFunc) {
+	c.audio = f
+}
+
+// SetSampleRate sets the rate SetAudioCallback samples are generated at. A
+// rate of 0 or less restores DefaultSampleRate.
+func (c *chip8) SetSampleRate(hz int) {
+	if hz <= 0 {
+		hz = DefaultSampleRate
+	}
+	c.sampleRate = hz
+}
+
+// fillAudio generates one frame of samples for the callback, which is
+// handed them once mu is released.
+func (c *chip8) fillAudio() []float32 {
+	n := c.sampleRate / 60
+	if cap(c.audioBuf) < n {
+		c.audioBuf = make([]float32, n)
+	}
+	buf := c.audioBuf[:n]
+	if c.soundTimer == 0 {
+		for i := range buf {
+			buf[i] = 0
+		}
+		c.audioPhase = 0
+		return buf
+	}
+
+	// One period is the whole 128-bit pattern, or a high then a low half
+	// for the plain tone
+	freq := float64(ToneFrequency)
+	if c.hasPattern {
+		freq = PatternRate / 128.0
+	}
+	step := freq / float64(c.sampleRate)
+	for i := range buf {
+		var high bool
+		if c.hasPattern {
+			bit := int(c.audioPhase * 128)
+			high = c.audioPattern[bit/8]&(0x80>>(bit%8)) != 0
+		} else {
+			high = c.audioPhase < 0.5
+		}
+		buf[i] = -audioVolume
+		if high {
+			buf[i] = audioVolume
+		}
+		c.audioPhase += step
+		c.audioPhase -= float64(int(c.audioPhase))
+	}
+	return buf
+}
@@ -0,0 +1,202 @@
+package interpreter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type spySound struct {
+	starts, stops int
+}
+
+func (s *spySound) Start() { s.starts++ }
+func (s *spySound) Stop()  { s.stops++ }
+
+func TestSoundStartStop(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x60, 0x02, 0xF0, 0x18, 0xF0, 0x18} // LD V0, 2; LD ST, V0; LD ST, V0
+	chip8.LoadBytes(0x200, testBytes)
+	spy := &spySound{}
+	chip8.SetSound(spy)
+
+	chip8.Step()
+	chip8.Step()
+	assert.Equal(t, 1, spy.starts)
+	assert.Equal(t, 0, spy.stops)
+
+	// Reloading a running timer must not restart the beep
+	chip8.Step()
+	assert.Equal(t, 1, spy.starts)
+
+	chip8.TickTimers(1)
+	assert.Equal(t, 0, spy.stops)
+	chip8.TickTimers(1)
+	assert.Equal(t, 1, spy.stops)
+	assert.Equal(t, byte(0), chip8.soundTimer)
+
+	chip8.TickTimers(1)
+	assert.Equal(t, 1, spy.stops)
+}
+
+func TestSoundCallbacksMaySnapshot(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x60, 0x05, 0xF0, 0x18, 0x12, 0x04}) // LD V0, 5; LD ST, V0; JP 0x204
+	var states []State
+	chip8.SetSoundStateCallback(func(playing bool) { states = append(states, chip8.Snapshot()) })
+	var frames int
+	chip8.SetAudioCallback(func(buf []float32, sampleRate int) {
+		chip8.Snapshot()
+		frames++
+	})
+
+	done := make(chan error)
+	go func() {
+		err := chip8.RunCycles(2)
+		if err == nil {
+			err = chip8.RunFrame()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("deadlocked calling Snapshot from a sound callback")
+	}
+
+	if assert.Len(t, states, 1) {
+		assert.Equal(t, byte(5), states[0].SoundTimer)
+	}
+	assert.Equal(t, 1, frames)
+}
+
+func TestSoundStateCallback(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0x60, 0x02, 0xF0, 0x18, 0xF0, 0x18} // LD V0, 2; LD ST, V0; LD ST, V0
+	chip8.LoadBytes(0x200, testBytes)
+	var states []bool
+	chip8.SetSoundStateCallback(func(playing bool) { states = append(states, playing) })
+
+	assert.NoError(t, chip8.RunCycles(3))
+	assert.Equal(t, []bool{true}, states, "reloading a running timer is no transition")
+
+	chip8.TickTimers(1)
+	assert.Equal(t, []bool{true}, states)
+	chip8.TickTimers(1)
+	assert.Equal(t, []bool{true, false}, states)
+
+	chip8.SetSoundStateCallback(nil)
+	chip8.SetSoundTimer(1)
+	assert.Equal(t, []bool{true, false}, states)
+}
+
+type spyPatternSound struct {
+	spySound
+	pattern [16]byte
+}
+
+func (s *spyPatternSound) SetPattern(pattern [16]byte) { s.pattern = pattern }
+
+func TestLoadAudioPattern(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	testBytes := []byte{0xA3, 0x00, 0xF0, 0x02} // LD I, 0x300; AUDIO
+	chip8.LoadBytes(0x200, testBytes)
+	pattern := []byte{
+		0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00,
+		0xF0, 0xF0, 0xF0, 0xF0, 0x0F, 0x0F, 0x0F, 0x0F,
+	}
+	chip8.LoadBytes(0x300, pattern)
+	spy := &spyPatternSound{}
+	chip8.SetSound(spy)
+
+	assert.NoError(t, chip8.Step())
+	assert.NoError(t, chip8.Step())
+
+	assert.Equal(t, pattern, chip8.audioPattern[:])
+	assert.Equal(t, pattern, spy.pattern[:])
+}
+
+func TestSetSampleRateInvalid(t *testing.T) {
+	for _, hz := range []int{0, -44100} {
+		chip8 := newTestChip8(DefaultMemorySize)
+		chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+		var got int
+		chip8.SetAudioCallback(func(buf []float32, sampleRate int) {
+			got = sampleRate
+		})
+
+		chip8.SetSampleRate(hz)
+		assert.NotPanics(t, func() { chip8.RunFrame() }, "%d Hz", hz)
+		assert.Equal(t, DefaultSampleRate, got, "%d Hz", hz)
+	}
+}
+
+func TestAudioCallback(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetSampleRate(6000)
+	chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+	var frames [][]float32
+	chip8.SetAudioCallback(func(buf []float32, sampleRate int) {
+		assert.Equal(t, 6000, sampleRate)
+		frames = append(frames, append([]float32(nil), buf...))
+	})
+
+	chip8.SetSoundTimer(1)
+	assert.NoError(t, chip8.RunFrame())
+	assert.NoError(t, chip8.RunFrame())
+
+	if assert.Len(t, frames, 2) {
+		assert.Len(t, frames[0], 100)
+		high, low := 0, 0
+		for _, v := range frames[0] {
+			switch {
+			case v > 0:
+				high++
+			case v < 0:
+				low++
+			}
+		}
+		assert.Equal(t, 100, high+low, "no silent samples while the timer runs")
+		assert.InDelta(t, 50, high, 4)
+		for _, v := range frames[1] {
+			assert.Zero(t, v)
+		}
+	}
+}
+
+func TestAudioCallbackPattern(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.SetSampleRate(4000) // One pattern bit per sample
+	chip8.SetClockSpeed(180)  // Run all three instructions in one frame
+	// LD I, 0x300; AUDIO; JP 0x204
+	chip8.LoadBytes(0x200, []byte{0xA3, 0x00, 0xF0, 0x02, 0x12, 0x04})
+	pattern := make([]byte, 16)
+	pattern[0] = 0xC0
+	chip8.LoadBytes(0x300, pattern)
+	var samples []float32
+	chip8.SetAudioCallback(func(buf []float32, sampleRate int) {
+		samples = append(samples, buf...)
+	})
+
+	chip8.SetSoundTimer(2)
+	assert.NoError(t, chip8.RunFrame())
+
+	if assert.Len(t, samples, 66) {
+		assert.Positive(t, samples[0])
+		assert.Positive(t, samples[1])
+		for _, v := range samples[2:] {
+			assert.Negative(t, v)
+		}
+	}
+}
+
+func TestAudioCallbackOffByDefault(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	chip8.LoadBytes(0x200, []byte{0x12, 0x00}) // JP 0x200
+	chip8.SetSoundTimer(5)
+
+	assert.NoError(t, chip8.RunFrame())
+	assert.Nil(t, chip8.audioBuf)
+}
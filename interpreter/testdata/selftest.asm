@@ -0,0 +1,204 @@
+; Opcode self test. Every check leaves VC = 1 when it passes and then calls
+; mark, which draws a tick (pass) or a cross (fail) left to right, ten to a
+; row. The ROM ends on a jump to itself.
+;
+; Rebuild selftest.ch8 after editing with: go test ./interpreter -run Golden -update
+
+        LD VA, 0                ; x of the next mark
+        LD VB, 0                ; y of the next mark
+
+; 1: 3xkk / 4xkk
+        LD VC, 0
+        LD V0, 0x42
+        SE V0, 0x42
+        JP m1
+        SNE V0, 0x42
+        LD VC, 1
+m1:     CALL mark
+
+; 2: 5xy0 / 9xy0
+        LD VC, 0
+        LD V0, 7
+        LD V2, 7
+        SE V0, V2
+        JP m2
+        SNE V0, V2
+        LD VC, 1
+m2:     CALL mark
+
+; 3: 7xkk wraps without touching VF
+        LD VC, 0
+        LD VF, 0
+        LD V0, 0xFF
+        ADD V0, 2
+        SE V0, 1
+        JP m3
+        SE VF, 0
+        JP m3
+        LD VC, 1
+m3:     CALL mark
+
+; 4: 8xy4 carry
+        LD VC, 0
+        LD V0, 0xFF
+        LD V2, 2
+        ADD V0, V2
+        SE V0, 1
+        JP m4
+        SE VF, 1
+        JP m4
+        LD VC, 1
+m4:     CALL mark
+
+; 5: 8xy5 / 8xy7 borrow
+        LD VC, 0
+        LD V0, 5
+        LD V2, 3
+        SUB V0, V2
+        SE V0, 2
+        JP m5
+        SE VF, 1
+        JP m5
+        LD V0, 1
+        SUBN V0, V2
+        SE V0, 2
+        JP m5
+        SE VF, 1
+        JP m5
+        LD VC, 1
+m5:     CALL mark
+
+; 6: 8xy6 / 8xyE shifts
+        LD VC, 0
+        LD V0, 3
+        SHR V0
+        SE V0, 1
+        JP m6
+        SE VF, 1
+        JP m6
+        LD V0, 0x81
+        SHL V0
+        SE V0, 2
+        JP m6
+        SE VF, 1
+        JP m6
+        LD VC, 1
+m6:     CALL mark
+
+; 7: 8xy1 / 8xy2 / 8xy3 logic
+        LD VC, 0
+        LD V0, 0xF0
+        LD V2, 0x0F
+        OR V0, V2
+        SE V0, 0xFF
+        JP m7
+        LD V2, 0x3C
+        AND V0, V2
+        SE V0, 0x3C
+        JP m7
+        LD V2, 0xFF
+        XOR V0, V2
+        SE V0, 0xC3
+        JP m7
+        LD VC, 1
+m7:     CALL mark
+
+; 8: Fx33 / Fx55 / Fx65
+        LD VC, 0
+        LD V0, 234
+        LD I, scratch
+        LD B, V0
+        LD V2, [I]
+        SE V0, 2
+        JP m8
+        SE V1, 3
+        JP m8
+        SE V2, 4
+        JP m8
+        LD V0, 0x69
+        LD I, scratch           ; Fx55/Fx65 may have moved I
+        LD [I], V0
+        LD V0, 0
+        LD I, scratch
+        LD V0, [I]
+        SE V0, 0x69
+        JP m8
+        LD VC, 1
+m8:     CALL mark
+
+; 9: Fx1E
+        LD VC, 0
+        LD I, table
+        LD V0, 2
+        ADD I, V0
+        LD V0, [I]
+        SE V0, 0x33
+        JP m9
+        LD VC, 1
+m9:     CALL mark
+
+; 10: 2nnn / 00EE nest
+        LD VC, 0
+        LD V0, 0
+        CALL inc2
+        SE V0, 2
+        JP m10
+        LD VC, 1
+m10:    CALL mark
+
+; 11: Bnnn
+        LD VC, 0
+        LD V0, 2
+        JP V0, jtab
+jtab:   JP m11
+        LD VC, 1
+m11:    CALL mark
+
+; 12: Fx15 / Fx07
+        LD VC, 0
+        LD V0, 10
+        LD DT, V0
+        LD V2, DT
+        SE V2, 10
+        JP m12
+        LD VC, 1
+m12:    CALL mark
+
+; 13: Dxyn collision
+        LD VC, 0
+        LD V0, 0
+        LD V2, 26
+        LD I, tick
+        DRW V0, V2, 5
+        SE VF, 0
+        JP m13
+        DRW V0, V2, 5
+        SE VF, 1
+        JP m13
+        LD VC, 1
+m13:    CALL mark
+
+end:    JP end
+
+; mark draws the tick or cross for VC at (VA, VB) and moves along.
+mark:   LD I, cross
+        SNE VC, 1
+        LD I, tick
+        DRW VA, VB, 5
+        ADD VA, 6
+        SE VA, 60
+        RET
+        LD VA, 0
+        ADD VB, 6
+        RET
+
+inc2:   ADD V0, 1
+        CALL inc1
+        RET
+inc1:   ADD V0, 1
+        RET
+
+tick:   DB 0x08, 0x10, 0xA0, 0x40, 0x00
+cross:  DB 0x88, 0x50, 0x20, 0x50, 0x88
+table:  DB 0x11, 0x22, 0x33
+scratch: DB 0, 0, 0
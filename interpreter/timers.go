@@ -0,0 +1,141 @@
+package interpreter
+
+import "time"
+
+// TimerPeriod is how often the delay and sound timers count down (60Hz).
+// Run executes one frame per period.
+const TimerPeriod = time.Second / 60
+
+// Clock is the time source Run paces frames with. Tests can install a fake
+// one with SetClock to run frames without waiting on the wall clock.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// SetClock installs the clock Run paces frames with. A nil Clock restores
+// the wall clock.
+func (c *chip8) SetClock(clk Clock) {
+	if clk == nil {
+		clk = realClock{}
+	}
+	c.clock = clk
+}
+
+// maxFrameLag is how far behind schedule waitFrame lets the host fall
+// before it drops the missed frames instead of catching up on them.
+const maxFrameLag = 5 * TimerPeriod
+
+// waitFrame sleeps until next, the time the next frame is due, and returns
+// when the one after it is. Frames are paced by these deadlines rather than
+// by sleeping a period after each one, so time spent executing never
+// accumulates as drift, and unlike a time.Ticker the Clock can be faked.
+// When the host has fallen behind it returns without sleeping, so the late
+// frames run back to back until they have caught up, unless it is more than
+// maxFrameLag behind: then the missed frames are dropped and pacing starts
+// over from now.
+func (c *chip8) waitFrame(next time.Time) time.Time {
+	now := c.clock.Now()
+	d := next.Sub(now)
+	switch {
+	case d > 0:
+		c.clock.Sleep(d)
+	case -d > maxFrameLag:
+		return now.Add(TimerPeriod)
+	}
+	return next.Add(TimerPeriod)
+}
+
+// updateTimers counts both timers down by one, stopping the beep when the
+// sound timer runs out, and records the time of the tick. It is the only
+// place either timer ticks, so the two stay in step.
+func (c *chip8) updateTimers() {
+	c.lastTick = c.clock.Now()
+	if c.delayTimer > 0 {
+		c.delayTimer--
+	}
+	if c.soundTimer > 0 {
+		c.setSoundTimer(c.soundTimer - 1)
+	}
+}
+
+// TickTimers counts both timers down by n ticks, as n frames would, without
+// running any instructions. They stop at zero, and the Sound and
+// SetSoundStateCallback are told when the beep stops, so tests can drive
+// timer-dependent code without Run and the clock.
+func (c *chip8) TickTimers(n int) {
+	for i := 0; i < n && (c.delayTimer > 0 || c.soundTimer > 0); i++ {
+		c.updateTimers()
+	}
+	c.notifySound()
+}
+
+// LastTimerTick returns when by the Clock the timers last ticked, at the end
+// of a frame or in TickTimers, or the zero time if they have not yet.
+func (c *chip8) LastTimerTick() time.Time {
+	return c.lastTick
+}
+
+// DelayTimer returns the current value of the delay timer.
+func (c *chip8) DelayTimer() byte {
+	return c.delayTimer
+}
+
+// SetDelayTimer sets the delay timer, as Fx15 does.
+func (c *chip8) SetDelayTimer(v byte) {
+	c.delayTimer = v
+}
+
+// SoundTimer returns the current value of the sound timer. The tone plays
+// while it is nonzero.
+func (c *chip8) SoundTimer() byte {
+	return c.soundTimer
+}
+
+// SetSoundTimer sets the sound timer, as Fx18 does, and notifies the Sound
+// and the SetSoundStateCallback function on 0 <-> nonzero transitions.
+func (c *chip8) SetSoundTimer(v byte) {
+	c.setSoundTimer(v)
+	c.notifySound()
+}
+
+// setSoundTimer is SetSoundTimer for code running with mu held. It only
+// queues the change, for notifySound to pass on once mu is released, so the
+// Sound and the callback may call Snapshot.
+func (c *chip8) setSoundTimer(v byte) {
+	playing := c.soundTimer > 0
+	c.soundTimer = v
+	if playing != (v > 0) {
+		c.soundChanges = append(c.soundChanges, v > 0)
+	}
+}
+
+// notifySound tells the Sound and the SetSoundStateCallback function about
+// the beep starting and stopping since it last ran. It must be called
+// without mu held.
+func (c *chip8) notifySound() {
+	for _, on := range c.soundChanges {
+		if on {
+			c.sound.Start()
+		} else {
+			c.sound.Stop()
+		}
+		if c.soundState != nil {
+			c.soundState(on)
+		}
+	}
+	c.soundChanges = c.soundChanges[:0]
+}
+
+// SetSoundStateCallback installs f to be told when the beep starts (true)
+// and stops (false), for a UI that shows a speaker icon or flashes the
+// screen instead of, or as well as, playing the tone through the Sound.
+// A nil f removes it again.
+func (c *chip8) SetSoundStateCallback(f func(playing bool)) {
+	c.soundState = f
+}
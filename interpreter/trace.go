@@ -0,0 +1,158 @@
+package interpreter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// TraceFunc is called before each instruction executes with the address it
+// was fetched from, the opcode and the registers at that point.
+type TraceFunc func(pc uint16, opcode uint16, regs [16]byte)
+
+// SetTraceFunc installs f to trace every executed instruction. A nil f turns
+// tracing off.
+func (c *chip8) SetTraceFunc(f TraceFunc) {
+	c.trace = f
+}
+
+// Binary trace format: the magic "C8TR", a flags byte, then one record per
+// instruction of big-endian PC and opcode. With traceRegisters set each
+// record continues with a big-endian mask of the registers that changed
+// since the previous record and the new value of each, lowest register
+// first.
+const (
+	traceMagic     = "C8TR"
+	traceRegisters = 0x01
+)
+
+// ErrBadTrace is returned by NewTraceReader for input that is not a binary
+// trace.
+var ErrBadTrace = errors.New("not a binary trace")
+
+// TraceRecord is one instruction decoded by TraceReader.
+type TraceRecord struct {
+	PC     uint16
+	Opcode uint16
+	Regs   [16]byte // Registers before the instruction, zero without registers
+}
+
+// TraceWriter writes a compact binary trace, a few bytes per instruction,
+// for post-mortem analysis of long runs. Install its Trace method with
+// SetTraceFunc and Flush it when done.
+type TraceWriter struct {
+	w     *bufio.Writer
+	regs  bool
+	prev  [16]byte
+	err   error
+	began bool
+}
+
+// NewTraceWriter returns a TraceWriter writing to w, including register
+// changes if regs is set.
+func NewTraceWriter(w io.Writer, regs bool) *TraceWriter {
+	return &TraceWriter{w: bufio.NewWriter(w), regs: regs}
+}
+
+// Trace is a TraceFunc appending one record. Write errors are kept and
+// returned by Flush.
+func (t *TraceWriter) Trace(pc uint16, opcode uint16, regs [16]byte) {
+	if t.err != nil {
+		return
+	}
+	if !t.began {
+		t.began = true
+		flags := byte(0)
+		if t.regs {
+			flags |= traceRegisters
+		}
+		t.w.WriteString(traceMagic)
+		t.w.WriteByte(flags)
+	}
+	var buf [6 + 16]byte
+	binary.BigEndian.PutUint16(buf[0:], pc)
+	binary.BigEndian.PutUint16(buf[2:], opcode)
+	n := 4
+	if t.regs {
+		mask := uint16(0)
+		n += 2
+		for i, v := range regs {
+			if v != t.prev[i] {
+				mask |= 1 << i
+				buf[n] = v
+				n++
+			}
+		}
+		binary.BigEndian.PutUint16(buf[4:], mask)
+		t.prev = regs
+	}
+	_, t.err = t.w.Write(buf[:n])
+}
+
+// Flush writes any buffered records and returns the first error hit.
+func (t *TraceWriter) Flush() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.w.Flush()
+}
+
+// TraceReader decodes a trace written by TraceWriter.
+type TraceReader struct {
+	r    *bufio.Reader
+	regs bool
+	prev [16]byte
+}
+
+// NewTraceReader reads the trace header from r. An empty r is an empty
+// trace.
+func NewTraceReader(r io.Reader) (*TraceReader, error) {
+	tr := &TraceReader{r: bufio.NewReader(r)}
+	var header [len(traceMagic) + 1]byte
+	if _, err := io.ReadFull(tr.r, header[:]); err != nil {
+		if err == io.EOF {
+			return tr, nil
+		}
+		return nil, ErrBadTrace
+	}
+	if string(header[:len(traceMagic)]) != traceMagic {
+		return nil, ErrBadTrace
+	}
+	tr.regs = header[len(traceMagic)]&traceRegisters != 0
+	return tr, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the trace.
+func (tr *TraceReader) Next() (TraceRecord, error) {
+	var buf [6]byte
+	n := 4
+	if tr.regs {
+		n = 6
+	}
+	if _, err := io.ReadFull(tr.r, buf[:n]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return TraceRecord{}, ErrBadTrace
+		}
+		return TraceRecord{}, err
+	}
+	rec := TraceRecord{
+		PC:     binary.BigEndian.Uint16(buf[0:]),
+		Opcode: binary.BigEndian.Uint16(buf[2:]),
+	}
+	if tr.regs {
+		mask := binary.BigEndian.Uint16(buf[4:])
+		for i := range tr.prev {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			v, err := tr.r.ReadByte()
+			if err != nil {
+				return TraceRecord{}, ErrBadTrace
+			}
+			tr.prev[i] = v
+		}
+		rec.Regs = tr.prev
+	}
+	return rec, nil
+}
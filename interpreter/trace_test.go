@@ -0,0 +1,106 @@
+package interpreter
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceFunc(t *testing.T) {
+	chip8 := newTestChip8(DefaultMemorySize)
+	// LD V2, 0x69; JP 0x206; -; ADD V2, 1
+	testBytes := []byte{0x62, 0x69, 0x12, 0x06, 0x00, 0x00, 0x72, 0x01}
+	chip8.LoadBytes(0x200, testBytes)
+
+	var pcs, ops []uint16
+	var regs [][16]byte
+	chip8.SetTraceFunc(func(pc uint16, opcode uint16, v [16]byte) {
+		pcs = append(pcs, pc)
+		ops = append(ops, opcode)
+		regs = append(regs, v)
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, chip8.Step())
+	}
+
+	assert.Equal(t, []uint16{0x200, 0x202, 0x206}, pcs)
+	assert.Equal(t, []uint16{0x6269, 0x1206, 0x7201}, ops)
+	assert.Equal(t, uint8(0x00), regs[0][2])
+	assert.Equal(t, uint8(0x69), regs[2][2])
+
+	chip8.SetTraceFunc(nil)
+	chip8.PC = 0x200
+	assert.NoError(t, chip8.Step())
+	assert.Len(t, pcs, 3)
+}
+
+func TestBinaryTraceRoundTrip(t *testing.T) {
+	for _, regs := range []bool{true, false} {
+		chip8 := newTestChip8(DefaultMemorySize)
+		// LD V2, 0x69; JP 0x206; -; ADD V2, 1; LD V0, V2
+		testBytes := []byte{0x62, 0x69, 0x12, 0x06, 0x00, 0x00, 0x72, 0x01, 0x80, 0x20}
+		chip8.LoadBytes(0x200, testBytes)
+		var want []TraceRecord
+		var buf bytes.Buffer
+		tw := NewTraceWriter(&buf, regs)
+		chip8.SetTraceFunc(func(pc uint16, opcode uint16, v [16]byte) {
+			rec := TraceRecord{PC: pc, Opcode: opcode}
+			if regs {
+				rec.Regs = v
+			}
+			want = append(want, rec)
+			tw.Trace(pc, opcode, v)
+		})
+
+		assert.NoError(t, chip8.RunCycles(4))
+		assert.NoError(t, tw.Flush())
+
+		tr, err := NewTraceReader(&buf)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		var got []TraceRecord
+		for {
+			rec, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if !assert.NoError(t, err) {
+				break
+			}
+			got = append(got, rec)
+		}
+		assert.Equal(t, want, got, "registers %v", regs)
+	}
+}
+
+func TestBinaryTraceSize(t *testing.T) {
+	var buf bytes.Buffer
+	tw := NewTraceWriter(&buf, true)
+	var regs [16]byte
+	tw.Trace(0x200, 0x6269, regs)
+	regs[2] = 0x69
+	tw.Trace(0x202, 0x1206, regs)
+	assert.NoError(t, tw.Flush())
+
+	// Header, a record with no changes, a record with one
+	assert.Equal(t, 5+6+7, buf.Len())
+}
+
+func TestBinaryTraceBadInput(t *testing.T) {
+	_, err := NewTraceReader(bytes.NewReader([]byte("not a trace")))
+	assert.ErrorIs(t, err, ErrBadTrace)
+
+	tr, err := NewTraceReader(bytes.NewReader([]byte("C8TR\x00\x02\x00\x62")))
+	assert.NoError(t, err)
+	_, err = tr.Next()
+	assert.ErrorIs(t, err, ErrBadTrace, "truncated record")
+
+	tr, err = NewTraceReader(bytes.NewReader(nil))
+	assert.NoError(t, err)
+	_, err = tr.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
 
@@ -8,15 +12,84 @@ import (
 )
 
 func main() {
-	chip8 := interpreter.NewChip8()
-	game, err := os.Open("./roms/space_invaders.ch8")
+	romPath := flag.String("rom", "./roms/space_invaders.ch8", "path to the ROM to run, - for stdin")
+	clock := flag.Int("clock", 60, "instructions executed per second")
+	profile := flag.String("profile", "schip", "machine profile: chip8, schip or xochip")
+	scale := flag.Int("scale", 1, "terminal cells per CHIP-8 pixel")
+	debug := flag.Bool("debug", false, "start in the debugger instead of running")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	machineProfile, err := profileForName(*profile)
+	if err != nil {
+		log.Fatalf("|| %s", err)
+	}
+	if *clock <= 0 || *scale <= 0 {
+		log.Fatalf("|| -clock and -scale must be positive")
+	}
+	if *debug && *romPath == "-" {
+		log.Fatalf("|| -debug reads commands from stdin, so the ROM cannot come from there")
+	}
+
+	chip8 := interpreter.NewChip8Profile(machineProfile)
+	chip8.SetClockSpeed(*clock)
+	chip8.SetScale(*scale)
+	if !*debug {
+		chip8.SetDisplay(newTerminal(os.Stdout))
+	}
+
+	err = loadRom(chip8, *romPath, os.Stdin)
 	if err != nil {
-		log.Panicf("Error opening file: %s", err)
+		log.Fatalf("|| Error loading ROM: %s", err)
+	}
+	if *debug {
+		err = newDebugger(chip8).repl(os.Stdin, os.Stdout)
+		if err != nil {
+			log.Fatalf("|| Debugger error: %s", err)
+		}
+		return
 	}
-	chip8.LoadBytes(0x50, interpreter.FontSet)
-	chip8.LoadRom(game)
 	err = chip8.Run()
+	if errors.Is(err, interpreter.ErrExit) {
+		return
+	}
 	if err != nil {
-		log.Fatal("|| Runtime error: %s", err)
+		log.Fatalf("|| Runtime error: %s", err)
+	}
+}
+
+// romLoader is the part of the interpreter loadRom uses.
+type romLoader interface {
+	LoadRom(r io.Reader) (int, error)
+	LoadRomFromFile(path string) (int, error)
+}
+
+// loadRom loads the ROM at path, or reads it from stdin when path is "-",
+// as in cat game.ch8 | chip-8 -rom -.
+func loadRom(c romLoader, path string, stdin io.Reader) error {
+	if path != "-" {
+		_, err := c.LoadRomFromFile(path)
+		return err
+	}
+	if _, err := c.LoadRom(stdin); err != nil {
+		return fmt.Errorf("loading stdin: %w", err)
+	}
+	return nil
+}
+
+// profileForName maps a -profile name to the interpreter profile, which
+// sets both the quirks and the memory size.
+func profileForName(name string) (interpreter.Profile, error) {
+	switch name {
+	case "chip8":
+		return interpreter.CosmacVIP, nil
+	case "schip":
+		return interpreter.SuperChipModern, nil
+	case "xochip":
+		return interpreter.XOChip, nil
 	}
+	return 0, fmt.Errorf("unknown profile %q", name)
 }
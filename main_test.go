@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/l4rma/chip-8/interpreter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileForName(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    interpreter.Profile
+	}{
+		{"chip8", interpreter.CosmacVIP},
+		{"schip", interpreter.SuperChipModern},
+		{"xochip", interpreter.XOChip},
+	}
+	for _, tt := range tests {
+		got, err := profileForName(tt.profile)
+		assert.NoError(t, err, tt.profile)
+		assert.Equal(t, tt.want, got, tt.profile)
+	}
+
+	_, err := profileForName("cosmac")
+	assert.Error(t, err)
+}
+
+func TestProfileMemory(t *testing.T) {
+	p, err := profileForName("xochip")
+	assert.NoError(t, err)
+	chip8 := interpreter.NewChip8Profile(p)
+
+	_, err = chip8.LoadAt(0xFFFE, []byte{0x12, 0x00})
+	assert.NoError(t, err, "xochip programs get 64KB")
+}
+
+func TestLoadRomStdin(t *testing.T) {
+	chip8 := interpreter.NewChip8()
+	stdin := bytes.NewReader([]byte{0x62, 0x69, 0x12, 0x00})
+
+	assert.NoError(t, loadRom(chip8, "-", stdin))
+	assert.Equal(t, []byte{0x62, 0x69, 0x12, 0x00}, chip8.PeekMemory(0x200, 4))
+}
+
+func TestLoadRomPath(t *testing.T) {
+	chip8 := interpreter.NewChip8()
+	stdin := bytes.NewReader([]byte{0xFF, 0xFF})
+
+	assert.NoError(t, loadRom(chip8, "roms/pong.ch8", stdin))
+	assert.NotEqual(t, []byte{0xFF, 0xFF}, chip8.PeekMemory(0x200, 2), "stdin left alone")
+	assert.ErrorIs(t, loadRom(chip8, "roms/missing.ch8", stdin), os.ErrNotExist)
+}
+
+func TestTerminalScale(t *testing.T) {
+	var buf bytes.Buffer
+	chip8 := interpreter.NewChip8()
+	chip8.SetScale(2)
+	chip8.SetDisplay(newTerminal(&buf))
+	chip8.SetPixel(0, 0, true)
+	chip8.Pause() // Only present the display
+
+	assert.NoError(t, chip8.RunFrame())
+
+	out := strings.TrimPrefix(buf.String(), "\x1b[2J\x1b[H")
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	assert.Len(t, lines, 2*32, "two rows per pixel")
+	assert.Equal(t, lines[0], lines[1])
+	assert.Equal(t, "████"+strings.Repeat(" ", 63*4), lines[0], "four cells per pixel")
+}
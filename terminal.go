@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+// XO-CHIP color index to terminal cell
+var shades = []rune{' ', '█', '▓', '▒'}
+
+// terminal renders frames with ANSI escape codes, drawing every CHIP-8 pixel
+// as scale rows of 2*scale cells to make up for tall terminal fonts.
+type terminal struct {
+	w       *bufio.Writer
+	scale   int
+	cleared bool
+}
+
+func newTerminal(w io.Writer) *terminal {
+	return &terminal{w: bufio.NewWriter(w), scale: 1}
+}
+
+// SetScale makes every CHIP-8 pixel n rows of 2*n cells.
+func (t *terminal) SetScale(n int) {
+	t.scale = n
+}
+
+func (t *terminal) Draw(frame [][]byte) {
+	if !t.cleared {
+		t.w.WriteString("\x1b[2J")
+		t.cleared = true
+	}
+	t.w.WriteString("\x1b[H")
+	for _, row := range frame {
+		for i := 0; i < t.scale; i++ {
+			for _, pixel := range row {
+				for j := 0; j < 2*t.scale; j++ {
+					t.w.WriteRune(shades[pixel&0x3])
+				}
+			}
+			t.w.WriteByte('\n')
+		}
+	}
+	t.w.Flush()
+}
@@ -0,0 +1,64 @@
+//go:build js && wasm
+
+package wasm
+
+import "syscall/js"
+
+// Register installs the session as a global chip8 object:
+//
+//	chip8.loadROM(bytes)      load a Uint8Array, returns an error string or null
+//	chip8.setClockSpeed(hz)
+//	chip8.step(n)             returns an error string or null
+//	chip8.runFrame()          returns an error string or null
+//	chip8.width(), chip8.height()
+//	chip8.framebuffer()       Uint8Array of width*height color indices
+//	chip8.keyDown(key), chip8.keyUp(key)
+func Register(s *Session) {
+	api := map[string]interface{}{
+		"loadROM": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			rom := make([]byte, args[0].Length())
+			js.CopyBytesToGo(rom, args[0])
+			return jsError(s.LoadROM(rom))
+		}),
+		"setClockSpeed": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			s.SetClockSpeed(args[0].Int())
+			return nil
+		}),
+		"step": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return jsError(s.Step(args[0].Int()))
+		}),
+		"runFrame": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return jsError(s.RunFrame())
+		}),
+		"width": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			w, _ := s.Dimensions()
+			return w
+		}),
+		"height": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			_, h := s.Dimensions()
+			return h
+		}),
+		"framebuffer": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			buf := s.Framebuffer()
+			arr := js.Global().Get("Uint8Array").New(len(buf))
+			js.CopyBytesToJS(arr, buf)
+			return arr
+		}),
+		"keyDown": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			s.KeyDown(uint8(args[0].Int()))
+			return nil
+		}),
+		"keyUp": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			s.KeyUp(uint8(args[0].Int()))
+			return nil
+		}),
+	}
+	js.Global().Set("chip8", js.ValueOf(api))
+}
+
+func jsError(err error) interface{} {
+	if err != nil {
+		return err.Error()
+	}
+	return nil
+}
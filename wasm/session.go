@@ -0,0 +1,88 @@
+// Package wasm wraps the interpreter in a small non-blocking API for running
+// in the browser, where the JS event loop drives the frame cadence instead
+// of Run's sleep loop. Build the WebAssembly binary from ./cmd/wasm with
+// GOOS=js GOARCH=wasm.
+package wasm
+
+import "github.com/l4rma/chip-8/interpreter"
+
+// machine is the part of the interpreter a Session drives.
+type machine interface {
+	LoadRomBytes(rom []byte) (int, error)
+	RunCycles(n int) error
+	RunFrame() error
+	Frame() [][]byte
+	Dimensions() (w, h int)
+	KeyDown(key uint8)
+	KeyUp(key uint8)
+	SetClockSpeed(hz int)
+}
+
+// Session is one interpreter. Nothing it does blocks.
+type Session struct {
+	m          machine
+	clockSpeed int
+}
+
+// NewSession returns a session with no ROM loaded.
+func NewSession() *Session {
+	s := &Session{clockSpeed: interpreter.DefaultClockSpeed}
+	s.reset()
+	return s
+}
+
+func (s *Session) reset() {
+	c := interpreter.NewChip8()
+	c.SetClockSpeed(s.clockSpeed)
+	s.m = c
+}
+
+// LoadROM starts over on a fresh machine with rom loaded at 0x200.
+func (s *Session) LoadROM(rom []byte) error {
+	s.reset()
+	_, err := s.m.LoadRomBytes(rom)
+	return err
+}
+
+// SetClockSpeed sets the instructions per second RunFrame executes at.
+func (s *Session) SetClockSpeed(hz int) {
+	s.clockSpeed = hz
+	s.m.SetClockSpeed(hz)
+}
+
+// Step executes n instructions without ticking the timers.
+func (s *Session) Step(n int) error {
+	return s.m.RunCycles(n)
+}
+
+// RunFrame executes one 60Hz frame: its instructions and a timer tick. Call
+// it from requestAnimationFrame or a 60Hz timer.
+func (s *Session) RunFrame() error {
+	return s.m.RunFrame()
+}
+
+// Dimensions returns the current display size.
+func (s *Session) Dimensions() (w, h int) {
+	return s.m.Dimensions()
+}
+
+// Framebuffer returns the display as w*h color indices, row by row, ready to
+// copy into a JS Uint8Array.
+func (s *Session) Framebuffer() []byte {
+	w, h := s.m.Dimensions()
+	buf := make([]byte, 0, w*h)
+	for _, row := range s.m.Frame() {
+		buf = append(buf, row...)
+	}
+	return buf
+}
+
+// KeyDown presses hex key 0x0-0xF.
+func (s *Session) KeyDown(key uint8) {
+	s.m.KeyDown(key)
+}
+
+// KeyUp releases hex key 0x0-0xF.
+func (s *Session) KeyUp(key uint8) {
+	s.m.KeyUp(key)
+}
@@ -0,0 +1,52 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/l4rma/chip-8/interpreter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionDraw(t *testing.T) {
+	s := NewSession()
+	// LD I, 0x20A; DRW V0, V0, 1; SKP V0; JP 0x204; CLS; DB 0xC0
+	rom := []byte{0xA2, 0x0A, 0xD0, 0x01, 0xE0, 0x9E, 0x12, 0x04, 0x00, 0xE0, 0xC0}
+	assert.NoError(t, s.LoadROM(rom))
+
+	assert.NoError(t, s.Step(2))
+	w, h := s.Dimensions()
+	assert.Equal(t, interpreter.LowResWidth, w)
+	assert.Equal(t, interpreter.LowResHeight, h)
+	fb := s.Framebuffer()
+	assert.Len(t, fb, w*h)
+	assert.Equal(t, []byte{1, 1, 0}, fb[:3])
+	assert.Equal(t, byte(0), fb[w])
+
+	// Spins on SKP until key 0 is down, then clears the screen
+	assert.NoError(t, s.RunFrame())
+	s.KeyDown(0x0)
+	assert.NoError(t, s.Step(3))
+	s.KeyUp(0x0)
+	assert.Equal(t, []byte{0, 0}, s.Framebuffer()[:2])
+}
+
+func TestSessionLoadROMStartsOver(t *testing.T) {
+	s := NewSession()
+	assert.NoError(t, s.LoadROM([]byte{0x00, 0xFF})) // HIGH
+	assert.NoError(t, s.Step(1))
+	w, _ := s.Dimensions()
+	assert.Equal(t, interpreter.HighResWidth, w)
+
+	assert.NoError(t, s.LoadROM([]byte{0x00, 0xE0}))
+	w, _ = s.Dimensions()
+	assert.Equal(t, interpreter.LowResWidth, w)
+
+	assert.Error(t, s.LoadROM(make([]byte, 0x1000)))
+}
+
+func TestSessionError(t *testing.T) {
+	s := NewSession()
+	assert.NoError(t, s.LoadROM([]byte{0xE0, 0x00}))
+	assert.Error(t, s.Step(1))
+	assert.Error(t, s.RunFrame())
+}